@@ -2,15 +2,137 @@ package main
 
 import (
 	account_repository "atm-simulation-console/internal/account/repository"
+	atm_batch "atm-simulation-console/internal/atm/batch"
 	atm_controller "atm-simulation-console/internal/atm/controller"
 	atm_service "atm-simulation-console/internal/atm/service"
+	"atm-simulation-console/internal/config"
+	interbank_switch "atm-simulation-console/internal/interbank/switch"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"flag"
+	"log"
+	"os"
 )
 
 func main() {
-	accountRepo := account_repository.NewAccountRepository()
-	atmSvc := atm_service.NewATMService(accountRepo)
+	os.Exit(run())
+}
+
+// run returns the exit code, so the data file is closed before main exits.
+func run() int {
+	dataFile := flag.String("data", "", "path to a JSON file for persisting accounts (in-memory when empty)")
+	configFile := flag.String("config", "", "path to a JSON file with bank rules (built-in defaults when empty)")
+	maxPINAttempts := flag.Int("max-pin-attempts", 0, "wrong PIN entries in a row before an account is blocked (overrides the config)")
+	dailyWithdraw := flag.Int("daily-withdraw-limit", 0, "maximum amount an account can withdraw per day (overrides the config)")
+	dailyTransfer := flag.Int("daily-transfer-limit", 0, "maximum amount an account can transfer per day (overrides the config)")
+	cutOff := flag.String("limit-cutoff", "", "time of day (HH:MM) at which daily limits reset (overrides the config)")
+	unblock := flag.String("unblock", "", "operator: unblock the given account number and exit")
+	batchFile := flag.String("batch", "", "run the scenario file non-interactively and print one JSON result per step")
+	maintenance := flag.Bool("operator", false, "operator: open maintenance mode before the customer session")
+	flag.Parse()
+
+	cfg := config.Default()
+	if *configFile != "" {
+		loaded, err := config.Load(*configFile)
+		if err != nil {
+			log.Printf("load config: %v", err)
+			return 1
+		}
+		cfg = loaded
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-pin-attempts":
+			cfg.MaxPINAttempts = *maxPINAttempts
+		case "daily-withdraw-limit":
+			cfg.Withdraw.DailyLimit = *dailyWithdraw
+		case "daily-transfer-limit":
+			cfg.Transfer.DailyLimit = *dailyTransfer
+		case "limit-cutoff":
+			cfg.LimitCutOff = *cutOff
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		log.Printf("invalid bank rules: %v", err)
+		return 1
+	}
+	if *batchFile != "" && *dataFile != "" {
+		// scenarios open accounts and move money that must not reach real data
+		log.Printf("-batch runs against in-memory accounts and cannot be used with -data")
+		return 2
+	}
+
+	var accountRepo account_repository.AccountStore = account_repository.NewAccountRepository()
+	if *dataFile != "" {
+		fileRepo, err := account_repository.NewFileAccountRepository(*dataFile, account_repository.DefaultCompactEvery)
+		if err != nil {
+			log.Printf("load accounts: %v", err)
+			return 1
+		}
+		defer fileRepo.Close()
+		accountRepo = fileRepo
+	}
+	ledger := transaction_repository.NewTransactionRepository()
+	atmSvc := atm_service.NewATMService(accountRepo, ledger, cfg)
+	sw := interbank_switch.NewSampleSwitch()
+	atmSvc.UseSwitch(sw)
+	if migrated, err := atmSvc.MigratePlaintextPINs(); err != nil {
+		log.Printf("migrate PINs: %v", err)
+		return 1
+	} else if migrated > 0 {
+		log.Printf("migrated %d plaintext PINs to hashes", migrated)
+	}
+	if err := atmSvc.EnsureFeeIncomeAccount(); err != nil {
+		log.Printf("open fee income account: %v", err)
+		return 1
+	}
+	if err := atmSvc.CheckOperatorCard(); err != nil {
+		log.Printf("check operator card: %v", err)
+		return 1
+	}
+
+	if *unblock != "" {
+		if err := atmSvc.UnblockAccount(*unblock); err != nil {
+			log.Printf("unblock %s: %v", *unblock, err)
+			return 1
+		}
+		log.Printf("account %s unblocked", *unblock)
+		return 0
+	}
 
-	atmController := atm_controller.NewATMController(atmSvc)
+	if *batchFile != "" {
+		return runBatch(atmSvc, sw, *batchFile)
+	}
 
+	atmController := atm_controller.NewATMController(atmSvc, os.Stdin, os.Stdout)
+
+	if *maintenance {
+		atmController.StartMaintenance()
+	}
 	atmController.Start()
+	return 0
+}
+
+// runBatch returns the exit code: 1 when any step failed. The switch runs on
+// the scenario's clock, so wait steps settle transfers.
+func runBatch(atmSvc *atm_service.ATMService, sw *interbank_switch.StubSwitch, path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("open scenario: %v", err)
+		return 1
+	}
+	defer f.Close()
+
+	atmSvc.AddSampleAccounts()
+	runner := atm_batch.NewRunner(atmSvc)
+	sw.SetClock(runner.Now)
+	summary, err := runner.Run(f, os.Stdout)
+	if err != nil {
+		log.Printf("run scenario: %v", err)
+		return 1
+	}
+	log.Printf("%d steps, %d failed", summary.Steps, summary.Failed)
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
 }
@@ -1,13 +1,94 @@
 package account_repository
 
+import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrAccountNotFound = errors.New("account not found")
+
+type AccountStatus string
+
+const (
+	StatusActive  AccountStatus = "active"
+	StatusBlocked AccountStatus = "blocked"
+	StatusDormant AccountStatus = "dormant"
+	StatusClosed  AccountStatus = "closed"
+)
+
+func (s AccountStatus) IsValid() bool {
+	switch s {
+	case StatusActive, StatusBlocked, StatusDormant, StatusClosed:
+		return true
+	}
+	return false
+}
+
 type Account struct {
-	AccountNumber string
-	Name          string
-	Pin           string
-	Balance       int
+	AccountNumber     string        `json:"account_number"`
+	Name              string        `json:"name"`
+	Pin               string        `json:"pin"`
+	Balance           int           `json:"balance"`
+	Status            AccountStatus `json:"status,omitempty"`
+	FailedPINAttempts int           `json:"failed_pin_attempts,omitempty"`
+	// Holds reserve part of Balance for transactions that have not settled
+	// yet.
+	Holds []Hold `json:"holds,omitempty"`
+	// WithdrawUsage and TransferUsage count what has left the account, so
+	// daily limits and free transactions survive a restart.
+	WithdrawUsage Usage `json:"withdraw_usage"`
+	TransferUsage Usage `json:"transfer_usage"`
+}
+
+// Hold reserves Amount plus Fee for the transaction with Reference, placed
+// At. Counterparty is kept for its ledger entry.
+type Hold struct {
+	Reference    string    `json:"reference"`
+	Amount       int       `json:"amount"`
+	Fee          int       `json:"fee,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	At           time.Time `json:"at"`
+}
+
+// Usage is the amount an account moved out through one channel since Day,
+// the start of the current limit day, and how many times it did so since
+// Month, the start of the current calendar month.
+type Usage struct {
+	Day    time.Time `json:"day"`
+	Amount int       `json:"amount"`
+	Month  time.Time `json:"month"`
+	Count  int       `json:"count"`
+}
+
+// Held is the part of Balance reserved by holds.
+func (a Account) Held() int {
+	held := 0
+	for _, hold := range a.Holds {
+		held += hold.Amount + hold.Fee
+	}
+	return held
+}
+
+// Available is the balance that can still be withdrawn or transferred.
+func (a Account) Available() int {
+	return a.Balance - a.Held()
+}
+
+// CurrentStatus treats accounts stored before statuses existed as active.
+func (a Account) CurrentStatus() AccountStatus {
+	if a.Status == "" {
+		return StatusActive
+	}
+	return a.Status
 }
 
+// AccountRepository is safe for concurrent use. A single store-wide lock
+// keeps multi-account operations such as Transfer free of lock ordering.
 type AccountRepository struct {
+	mu       sync.RWMutex
 	accounts map[string]Account
 }
 
@@ -18,11 +99,17 @@ func NewAccountRepository() *AccountRepository {
 }
 
 func (r *AccountRepository) AddAccount(account Account) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.accounts[account.AccountNumber] = account
 	return true
 }
 
 func (r *AccountRepository) FindAccount(number string) *Account {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	account, ok := r.accounts[number]
 	if !ok {
 		return nil
@@ -30,24 +117,145 @@ func (r *AccountRepository) FindAccount(number string) *Account {
 	return &account
 }
 
+func (r *AccountRepository) ListAccounts() []Account {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	accounts := make([]Account, 0, len(r.accounts))
+	for _, account := range r.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].AccountNumber < accounts[j].AccountNumber
+	})
+	return accounts
+}
+
 func (r *AccountRepository) GetBalance(number string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	return r.accounts[number].Balance
 }
 
-func (r *AccountRepository) Withdraw(number string, amount int) bool {
+func (r *AccountRepository) UpdateBalance(number string, balance int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	account, ok := r.accounts[number]
-	if !ok || account.Balance < amount {
+	if !ok {
 		return false
 	}
 
-	account.Balance -= amount
+	account.Balance = balance
 	r.accounts[number] = account
 	return true
 }
 
-func (r *AccountRepository) Deposit(number string, amount int) bool {
-	account := r.accounts[number]
+func (r *AccountRepository) UpdateAccount(number string, update func(account *Account) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[number]
+	if !ok {
+		return ErrAccountNotFound
+	}
+
+	// update gets its own holds, so changing them in place cannot reach the
+	// stored account before it is accepted
+	account.Holds = slices.Clone(account.Holds)
+	if err := update(&account); err != nil {
+		return err
+	}
+	account.AccountNumber = number
+	r.accounts[number] = account
+	return nil
+}
+
+func (r *AccountRepository) UpdateAccounts(numbers []string, update func(accounts []*Account) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	accounts := make([]*Account, 0, len(numbers))
+	for i, number := range numbers {
+		for _, seen := range numbers[:i] {
+			if seen == number {
+				return errors.New("account " + number + " given twice")
+			}
+		}
+		account, ok := r.accounts[number]
+		if !ok {
+			return ErrAccountNotFound
+		}
+		account.Holds = slices.Clone(account.Holds)
+		accounts = append(accounts, &account)
+	}
+
+	if err := update(accounts); err != nil {
+		return err
+	}
+	for i, number := range numbers {
+		accounts[i].AccountNumber = number
+		r.accounts[number] = *accounts[i]
+	}
+	return nil
+}
+
+func (r *AccountRepository) Withdraw(number string, amount int) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[number]
+	if !ok || account.Available() < amount {
+		return 0, false
+	}
+
+	account.Balance -= amount
+	r.accounts[number] = account
+	return account.Balance, true
+}
+
+func (r *AccountRepository) Deposit(number string, amount int) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[number]
+	if !ok {
+		return 0, false
+	}
+
 	account.Balance += amount
 	r.accounts[number] = account
-	return true
+	return account.Balance, true
+}
+
+func (r *AccountRepository) Transfer(srcNumber, destNumber string, amount int) (int, int, bool) {
+	if srcNumber == destNumber || amount <= 0 {
+		return 0, 0, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	src, ok := r.accounts[srcNumber]
+	if !ok || src.Available() < amount {
+		return 0, 0, false
+	}
+	dest, ok := r.accounts[destNumber]
+	if !ok {
+		return 0, 0, false
+	}
+
+	src.Balance -= amount
+	dest.Balance += amount
+	r.accounts[srcNumber] = src
+	r.accounts[destNumber] = dest
+	return src.Balance, dest.Balance, true
+}
+
+func (r *AccountRepository) remove(number string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.accounts, number)
 }
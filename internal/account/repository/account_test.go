@@ -1,6 +1,11 @@
 package account_repository
 
-import "testing"
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
 
 func TestFindAccount(t *testing.T) {
 	repo := NewAccountRepository()
@@ -39,13 +44,13 @@ func TestWithdraw(t *testing.T) {
 		Balance:       10000,
 	})
 
-	if !repo.Withdraw("123456", 5000) {
-		t.Errorf("expected true, got false")
+	if balance, ok := repo.Withdraw("123456", 5000); !ok || balance != 5000 {
+		t.Errorf("expected 5000/true, got %d/%v", balance, ok)
 	}
 	if repo.GetBalance("123456") != 5000 {
 		t.Errorf("expected 5000, got %d", repo.GetBalance("123456"))
 	}
-	if repo.Withdraw("123456", 6000) {
+	if _, ok := repo.Withdraw("123456", 6000); ok {
 		t.Errorf("expected false, got true")
 	}
 }
@@ -58,8 +63,248 @@ func TestDeposit(t *testing.T) {
 		Balance:       10000,
 	})
 
-	repo.Deposit("123456", 5000)
+	if balance, ok := repo.Deposit("123456", 5000); !ok || balance != 15000 {
+		t.Errorf("expected 15000/true, got %d/%v", balance, ok)
+	}
 	if repo.GetBalance("123456") != 15000 {
 		t.Errorf("expected 15000, got %d", repo.GetBalance("123456"))
 	}
+	if _, ok := repo.Deposit("999999", 5000); ok {
+		t.Errorf("expected false, got true")
+	}
+	if repo.FindAccount("999999") != nil {
+		t.Errorf("expected nil, got account")
+	}
+}
+
+func TestListAccounts(t *testing.T) {
+	repo := NewAccountRepository()
+	repo.AddAccount(Account{AccountNumber: "222222", Balance: 200})
+	repo.AddAccount(Account{AccountNumber: "111111", Balance: 100})
+
+	accounts := repo.ListAccounts()
+	if len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(accounts))
+	}
+	if accounts[0].AccountNumber != "111111" || accounts[1].AccountNumber != "222222" {
+		t.Errorf("expected accounts ordered by number, got %v", accounts)
+	}
+}
+
+func TestUpdateBalance(t *testing.T) {
+	repo := NewAccountRepository()
+	repo.AddAccount(Account{
+		AccountNumber: "123456",
+		Pin:           "1234",
+		Balance:       10000,
+	})
+
+	if !repo.UpdateBalance("123456", 2500) {
+		t.Errorf("expected true, got false")
+	}
+	if repo.GetBalance("123456") != 2500 {
+		t.Errorf("expected 2500, got %d", repo.GetBalance("123456"))
+	}
+	if repo.UpdateBalance("999999", 100) {
+		t.Errorf("expected false, got true")
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	repo := NewAccountRepository()
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+	repo.AddAccount(Account{AccountNumber: "654321", Balance: 500})
+
+	if src, dest, ok := repo.Transfer("123456", "654321", 400); !ok || src != 600 || dest != 900 {
+		t.Errorf("expected 600/900/true, got %d/%d/%v", src, dest, ok)
+	}
+	if repo.GetBalance("123456") != 600 || repo.GetBalance("654321") != 900 {
+		t.Errorf("expected 600/900, got %d/%d", repo.GetBalance("123456"), repo.GetBalance("654321"))
+	}
+
+	tests := []struct {
+		name   string
+		src    string
+		dest   string
+		amount int
+	}{
+		{name: "insufficient balance", src: "123456", dest: "654321", amount: 700},
+		{name: "same account", src: "123456", dest: "123456", amount: 100},
+		{name: "zero amount", src: "123456", dest: "654321", amount: 0},
+		{name: "negative amount", src: "123456", dest: "654321", amount: -100},
+		{name: "unknown destination", src: "123456", dest: "999999", amount: 100},
+		{name: "unknown source", src: "999999", dest: "654321", amount: 100},
+	}
+	for _, test := range tests {
+		if _, _, ok := repo.Transfer(test.src, test.dest, test.amount); ok {
+			t.Errorf("%s: expected false, got true", test.name)
+		}
+	}
+	if repo.GetBalance("123456") != 600 || repo.GetBalance("654321") != 900 {
+		t.Errorf("expected balances untouched, got %d/%d", repo.GetBalance("123456"), repo.GetBalance("654321"))
+	}
+}
+
+func TestHeldFundsCannotBeMoved(t *testing.T) {
+	repo := NewAccountRepository()
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000, Holds: []Hold{{Reference: "000001", Amount: 690, Fee: 10}}})
+	repo.AddAccount(Account{AccountNumber: "654321", Balance: 500})
+
+	if _, ok := repo.Withdraw("123456", 400); ok {
+		t.Errorf("expected withdraw of held funds to fail")
+	}
+	if _, _, ok := repo.Transfer("123456", "654321", 400); ok {
+		t.Errorf("expected transfer of held funds to fail")
+	}
+	if _, ok := repo.Withdraw("123456", 300); !ok {
+		t.Errorf("expected withdraw of available funds to succeed")
+	}
+	if acc := repo.FindAccount("123456"); acc.Balance != 700 || acc.Available() != 0 {
+		t.Errorf("expected balance 700 with nothing available, got %d/%d", acc.Balance, acc.Available())
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	repo := NewAccountRepository()
+	numbers := []string{"111111", "222222", "333333", "444444"}
+	for _, number := range numbers {
+		repo.AddAccount(Account{AccountNumber: number, Balance: 1000})
+	}
+
+	hammerAccounts(t, repo, numbers, 4000)
+}
+
+// hammerAccounts runs withdrawals, deposits and transfers against numbers
+// from many goroutines and checks that no money is created or lost.
+func hammerAccounts(t *testing.T, store AccountStore, numbers []string, startTotal int) {
+	t.Helper()
+
+	const workers = 16
+	const iterations = 200
+
+	var withdrawn, deposited atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				src := numbers[(w+i)%len(numbers)]
+				dest := numbers[(w+i+1)%len(numbers)]
+				amount := 10 + (w+i)%50
+
+				switch i % 3 {
+				case 0:
+					if _, ok := store.Withdraw(src, amount); ok {
+						withdrawn.Add(int64(amount))
+					}
+				case 1:
+					if _, ok := store.Deposit(src, amount); ok {
+						deposited.Add(int64(amount))
+					}
+				default:
+					store.Transfer(src, dest, amount)
+				}
+				store.GetBalance(dest)
+				store.FindAccount(src)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, account := range store.ListAccounts() {
+		if account.Balance < 0 {
+			t.Errorf("expected non-negative balance for %s, got %d", account.AccountNumber, account.Balance)
+		}
+		total += account.Balance
+	}
+	expected := startTotal - int(withdrawn.Load()) + int(deposited.Load())
+	if total != expected {
+		t.Errorf("expected total of %d, got %d", expected, total)
+	}
+}
+
+func TestUpdateAccount(t *testing.T) {
+	repo := NewAccountRepository()
+	repo.AddAccount(Account{AccountNumber: "123456", Pin: "111111", Balance: 100})
+
+	err := repo.UpdateAccount("123456", func(account *Account) error {
+		account.Pin = "222222"
+		account.AccountNumber = "999999"
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if acc := repo.FindAccount("123456"); acc == nil || acc.Pin != "222222" {
+		t.Errorf("expected pin 222222, got %v", acc)
+	}
+	if repo.FindAccount("999999") != nil {
+		t.Errorf("expected account number to stay unchanged")
+	}
+
+	rejected := errors.New("rejected")
+	err = repo.UpdateAccount("123456", func(account *Account) error {
+		account.Pin = "333333"
+		return rejected
+	})
+	if err != rejected {
+		t.Errorf("expected rejected, got %v", err)
+	}
+	if acc := repo.FindAccount("123456"); acc.Pin != "222222" {
+		t.Errorf("expected pin 222222, got %s", acc.Pin)
+	}
+
+	if err := repo.UpdateAccount("999999", func(*Account) error { return nil }); err != ErrAccountNotFound {
+		t.Errorf("expected ErrAccountNotFound, got %v", err)
+	}
+}
+
+func TestUpdateAccounts(t *testing.T) {
+	repo := NewAccountRepository()
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+	repo.AddAccount(Account{AccountNumber: "654321", Balance: 500})
+
+	move := func(amount int) func(accounts []*Account) error {
+		return func(accounts []*Account) error {
+			accounts[0].Balance -= amount
+			if accounts[0].Balance < 0 {
+				return errors.New("overdrawn")
+			}
+			accounts[1].Balance += amount
+			return nil
+		}
+	}
+	if err := repo.UpdateAccounts([]string{"123456", "654321"}, move(400)); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := repo.UpdateAccounts([]string{"123456", "654321"}, move(700)); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if repo.GetBalance("123456") != 600 || repo.GetBalance("654321") != 900 {
+		t.Errorf("expected 600/900, got %d/%d", repo.GetBalance("123456"), repo.GetBalance("654321"))
+	}
+
+	if err := repo.UpdateAccounts([]string{"123456", "999999"}, move(100)); err != ErrAccountNotFound {
+		t.Errorf("expected ErrAccountNotFound, got %v", err)
+	}
+	if err := repo.UpdateAccounts([]string{"123456", "123456"}, move(100)); err == nil {
+		t.Errorf("expected error for the same account twice, got nil")
+	}
+	if repo.GetBalance("123456") != 600 {
+		t.Errorf("expected 600, got %d", repo.GetBalance("123456"))
+	}
+}
+
+func TestCurrentStatus(t *testing.T) {
+	if status := (Account{}).CurrentStatus(); status != StatusActive {
+		t.Errorf("expected active for legacy account, got %q", status)
+	}
+	if status := (Account{Status: StatusClosed}).CurrentStatus(); status != StatusClosed {
+		t.Errorf("expected closed, got %q", status)
+	}
+	if AccountStatus("frozen").IsValid() {
+		t.Errorf("expected frozen to be invalid")
+	}
 }
@@ -0,0 +1,292 @@
+package account_repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const DefaultCompactEvery = 100
+
+type accountFile struct {
+	Seq      int64     `json:"seq"`
+	Accounts []Account `json:"accounts"`
+}
+
+// FileAccountRepository keeps accounts in memory and makes every mutation
+// durable by appending it to a journal before acknowledging it. The journal
+// is folded into the JSON snapshot every compactEvery entries. Mutations
+// are serialized so journal order always matches the order they applied.
+type FileAccountRepository struct {
+	mu           sync.Mutex
+	path         string
+	journal      *journal
+	store        *AccountRepository
+	seq          int64
+	pending      int
+	compactEvery int
+}
+
+type accountChange struct {
+	number string
+	prev   *Account
+}
+
+func NewFileAccountRepository(path string, compactEvery int) (*FileAccountRepository, error) {
+	if compactEvery <= 0 {
+		compactEvery = DefaultCompactEvery
+	}
+	r := &FileAccountRepository{
+		path:         path,
+		store:        NewAccountRepository(),
+		compactEvery: compactEvery,
+	}
+	replayed, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	r.journal, err = openJournal(journalPath(path))
+	if err != nil {
+		return nil, err
+	}
+	// start from a clean journal so a torn tail is never appended to
+	if replayed {
+		if err := r.compact(); err != nil {
+			r.journal.close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *FileAccountRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.journal.close()
+}
+
+func (r *FileAccountRepository) AddAccount(account Account) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := r.before(account.AccountNumber)
+	r.store.AddAccount(account)
+	return r.commit(changes)
+}
+
+func (r *FileAccountRepository) FindAccount(number string) *Account {
+	return r.store.FindAccount(number)
+}
+
+func (r *FileAccountRepository) ListAccounts() []Account {
+	return r.store.ListAccounts()
+}
+
+func (r *FileAccountRepository) GetBalance(number string) int {
+	return r.store.GetBalance(number)
+}
+
+func (r *FileAccountRepository) UpdateBalance(number string, balance int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := r.before(number)
+	if !r.store.UpdateBalance(number, balance) {
+		return false
+	}
+	return r.commit(changes)
+}
+
+func (r *FileAccountRepository) UpdateAccount(number string, update func(account *Account) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := r.before(number)
+	if err := r.store.UpdateAccount(number, update); err != nil {
+		return err
+	}
+	if !r.commit(changes) {
+		return errors.New("failed to persist account " + number)
+	}
+	return nil
+}
+
+func (r *FileAccountRepository) UpdateAccounts(numbers []string, update func(accounts []*Account) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := r.before(numbers...)
+	if err := r.store.UpdateAccounts(numbers, update); err != nil {
+		return err
+	}
+	if !r.commit(changes) {
+		return errors.New("failed to persist accounts " + strings.Join(numbers, ", "))
+	}
+	return nil
+}
+
+func (r *FileAccountRepository) Withdraw(number string, amount int) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := r.before(number)
+	balance, ok := r.store.Withdraw(number, amount)
+	if !ok || !r.commit(changes) {
+		return 0, false
+	}
+	return balance, true
+}
+
+func (r *FileAccountRepository) Deposit(number string, amount int) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := r.before(number)
+	balance, ok := r.store.Deposit(number, amount)
+	if !ok || !r.commit(changes) {
+		return 0, false
+	}
+	return balance, true
+}
+
+func (r *FileAccountRepository) Transfer(srcNumber, destNumber string, amount int) (int, int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := r.before(srcNumber, destNumber)
+	srcBalance, destBalance, ok := r.store.Transfer(srcNumber, destNumber, amount)
+	if !ok || !r.commit(changes) {
+		return 0, 0, false
+	}
+	return srcBalance, destBalance, true
+}
+
+func (r *FileAccountRepository) before(numbers ...string) []accountChange {
+	changes := make([]accountChange, 0, len(numbers))
+	for _, number := range numbers {
+		changes = append(changes, accountChange{number: number, prev: r.store.FindAccount(number)})
+	}
+	return changes
+}
+
+// commit journals the current state of the changed accounts as one entry.
+// When the journal write fails the in-memory changes are undone, so the map
+// never holds anything that would be lost on a crash.
+func (r *FileAccountRepository) commit(changes []accountChange) bool {
+	entry := journalEntry{Seq: r.seq + 1}
+	for _, change := range changes {
+		entry.Accounts = append(entry.Accounts, *r.store.FindAccount(change.number))
+	}
+
+	if err := r.journal.append(entry); err != nil {
+		for _, change := range changes {
+			if change.prev == nil {
+				r.store.remove(change.number)
+			} else {
+				r.store.AddAccount(*change.prev)
+			}
+		}
+		return false
+	}
+	r.seq = entry.Seq
+
+	r.pending++
+	if r.pending >= r.compactEvery {
+		// the entry is already durable; a failed compaction is retried on
+		// the next mutation
+		r.compact()
+	}
+	return true
+}
+
+// compact writes a snapshot covering every journaled entry and then empties
+// the journal. A crash between the two steps is harmless: entries at or
+// below the snapshot sequence are skipped on replay.
+func (r *FileAccountRepository) compact() error {
+	if err := r.save(); err != nil {
+		return err
+	}
+	if err := r.journal.truncate(); err != nil {
+		return err
+	}
+	r.pending = 0
+	return nil
+}
+
+func (r *FileAccountRepository) load() (bool, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if err == nil {
+		var file accountFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return false, fmt.Errorf("parse %s: %w", r.path, err)
+		}
+		for _, account := range file.Accounts {
+			r.store.AddAccount(account)
+		}
+		r.seq = file.Seq
+	}
+
+	entries, err := readJournal(journalPath(r.path))
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if entry.Seq <= r.seq {
+			continue
+		}
+		for _, account := range entry.Accounts {
+			r.store.AddAccount(account)
+		}
+		r.seq = entry.Seq
+	}
+	return journalHasData(r.path), nil
+}
+
+func (r *FileAccountRepository) save() error {
+	data, err := json.MarshalIndent(accountFile{Seq: r.seq, Accounts: r.store.ListAccounts()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.path, data)
+}
+
+func journalPath(path string) string {
+	return path + ".journal"
+}
+
+func journalHasData(path string) bool {
+	info, err := os.Stat(journalPath(path))
+	return err == nil && info.Size() > 0
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it into
+// place, so readers only ever see the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,372 @@
+package account_repository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileAccountRepositoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	repo, err := NewFileAccountRepository(path, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.AddAccount(Account{AccountNumber: "123456", Pin: "111111", Balance: 1000})
+	repo.AddAccount(Account{AccountNumber: "654321", Pin: "222222", Balance: 50})
+
+	if _, ok := repo.Withdraw("123456", 300); !ok {
+		t.Errorf("expected true, got false")
+	}
+	if _, ok := repo.Deposit("654321", 300); !ok {
+		t.Errorf("expected true, got false")
+	}
+	if _, ok := repo.Withdraw("654321", 1000); ok {
+		t.Errorf("expected false, got true")
+	}
+	repo.Close()
+
+	reopened, err := NewFileAccountRepository(path, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer reopened.Close()
+	if reopened.GetBalance("123456") != 700 {
+		t.Errorf("expected 700, got %d", reopened.GetBalance("123456"))
+	}
+	if reopened.GetBalance("654321") != 350 {
+		t.Errorf("expected 350, got %d", reopened.GetBalance("654321"))
+	}
+	if acc := reopened.FindAccount("123456"); acc == nil || acc.Pin != "111111" {
+		t.Errorf("expected account with pin 111111, got %v", acc)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 2 {
+		t.Errorf("expected only the snapshot and journal to remain, got %d entries", len(entries))
+	}
+}
+
+func TestFileAccountRepositoryMissingFile(t *testing.T) {
+	repo, err := NewFileAccountRepository(filepath.Join(t.TempDir(), "missing.json"), 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer repo.Close()
+	if len(repo.ListAccounts()) != 0 {
+		t.Errorf("expected no accounts, got %d", len(repo.ListAccounts()))
+	}
+}
+
+func TestFileAccountRepositoryCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	os.WriteFile(path, []byte("{not json"), 0o600)
+
+	if _, err := NewFileAccountRepository(path, 0); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestFileAccountRepositoryRollsBackOnWriteFailure(t *testing.T) {
+	repo, err := NewFileAccountRepository(filepath.Join(t.TempDir(), "accounts.json"), 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+
+	// make every journal append fail
+	repo.journal.close()
+
+	if _, ok := repo.Withdraw("123456", 100); ok {
+		t.Errorf("expected false, got true")
+	}
+	if repo.GetBalance("123456") != 1000 {
+		t.Errorf("expected 1000, got %d", repo.GetBalance("123456"))
+	}
+	if repo.AddAccount(Account{AccountNumber: "654321"}) {
+		t.Errorf("expected false, got true")
+	}
+	if repo.FindAccount("654321") != nil {
+		t.Errorf("expected nil, got account")
+	}
+}
+
+// failingFile tears the next write in half or fails the next sync.
+type failingFile struct {
+	journalFile
+	tearWrite, failSync bool
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	if f.tearWrite {
+		f.tearWrite = false
+		n, _ := f.journalFile.Write(p[:len(p)/2])
+		return n, errors.New("no space left on device")
+	}
+	return f.journalFile.Write(p)
+}
+
+func (f *failingFile) Sync() error {
+	if f.failSync {
+		f.failSync = false
+		return errors.New("sync failed")
+	}
+	return f.journalFile.Sync()
+}
+
+func TestFileAccountRepositoryFailedAppendLeavesNoTrace(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		file failingFile
+	}{
+		{"torn write", failingFile{tearWrite: true}},
+		{"failed sync", failingFile{failSync: true}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "accounts.json")
+			repo, err := NewFileAccountRepository(path, 1000)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+
+			file := tt.file
+			file.journalFile = repo.journal.file
+			repo.journal.file = &file
+			if _, ok := repo.Withdraw("123456", 100); ok {
+				t.Fatalf("expected the withdrawal to fail")
+			}
+			if _, ok := repo.Deposit("123456", 30); !ok {
+				t.Fatalf("expected the deposit to succeed")
+			}
+
+			recovered, err := NewFileAccountRepository(path, 1000)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			defer recovered.Close()
+			if recovered.GetBalance("123456") != 1030 {
+				t.Errorf("expected 1030, got %d", recovered.GetBalance("123456"))
+			}
+		})
+	}
+}
+
+func TestFileAccountRepositoryRecoversFromJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	repo, err := NewFileAccountRepository(path, 1000)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+	repo.Withdraw("123456", 100)
+	repo.Deposit("123456", 30)
+
+	// simulate a killed process: no Close, no snapshot written yet
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no snapshot before compaction, got %v", err)
+	}
+
+	recovered, err := NewFileAccountRepository(path, 1000)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer recovered.Close()
+	if recovered.GetBalance("123456") != 930 {
+		t.Errorf("expected 930, got %d", recovered.GetBalance("123456"))
+	}
+	if journalHasData(path) {
+		t.Errorf("expected journal to be compacted on startup")
+	}
+}
+
+func TestFileAccountRepositoryIgnoresTornJournalTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	repo, err := NewFileAccountRepository(path, 1000)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+	repo.Withdraw("123456", 100)
+	repo.journal.file.Write([]byte(`{"seq":3,"accounts":[{"account_number":"123456","bal`))
+
+	recovered, err := NewFileAccountRepository(path, 1000)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer recovered.Close()
+	if recovered.GetBalance("123456") != 900 {
+		t.Errorf("expected 900, got %d", recovered.GetBalance("123456"))
+	}
+}
+
+func TestFileAccountRepositoryRejectsCorruptJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	os.WriteFile(journalPath(path), []byte("garbage\n{\"seq\":1,\"accounts\":[]}\n"), 0o600)
+
+	if _, err := NewFileAccountRepository(path, 0); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestFileAccountRepositoryCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	repo, err := NewFileAccountRepository(path, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer repo.Close()
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+	repo.Withdraw("123456", 100)
+	repo.Withdraw("123456", 100)
+
+	entries, err := readJournal(journalPath(path))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 1 || entries[0].Seq != 3 {
+		t.Errorf("expected journal with only entry 3, got %v", entries)
+	}
+
+	// an entry already covered by the snapshot must not be applied again
+	stale := journalEntry{Seq: 2, Accounts: []Account{{AccountNumber: "123456", Balance: 900}}}
+	repo.journal.append(stale)
+
+	recovered, err := NewFileAccountRepository(path, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer recovered.Close()
+	if recovered.GetBalance("123456") != 800 {
+		t.Errorf("expected 800, got %d", recovered.GetBalance("123456"))
+	}
+}
+
+func TestFileAccountRepositoryTransferIsOneJournalEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	repo, err := NewFileAccountRepository(path, 1000)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+	repo.AddAccount(Account{AccountNumber: "654321", Balance: 500})
+
+	if _, _, ok := repo.Transfer("123456", "654321", 250); !ok {
+		t.Errorf("expected true, got false")
+	}
+
+	entries, err := readJournal(journalPath(path))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	last := entries[len(entries)-1]
+	if len(entries) != 3 || len(last.Accounts) != 2 {
+		t.Fatalf("expected transfer journaled as one entry with both accounts, got %v", entries)
+	}
+
+	repo.journal.close()
+	if _, _, ok := repo.Transfer("123456", "654321", 250); ok {
+		t.Errorf("expected false, got true")
+	}
+	if repo.GetBalance("123456") != 750 || repo.GetBalance("654321") != 750 {
+		t.Errorf("expected rollback to 750/750, got %d/%d", repo.GetBalance("123456"), repo.GetBalance("654321"))
+	}
+}
+
+func TestFileAccountRepositoryConcurrentAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	repo, err := NewFileAccountRepository(path, 50)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	numbers := []string{"111111", "222222", "333333"}
+	for _, number := range numbers {
+		repo.AddAccount(Account{AccountNumber: number, Balance: 1000})
+	}
+
+	hammerAccounts(t, repo, numbers, 3000)
+
+	expected := repo.ListAccounts()
+	repo.Close()
+
+	recovered, err := NewFileAccountRepository(path, 50)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer recovered.Close()
+	for _, account := range expected {
+		if recovered.GetBalance(account.AccountNumber) != account.Balance {
+			t.Errorf("expected %d for %s after replay, got %d", account.Balance, account.AccountNumber, recovered.GetBalance(account.AccountNumber))
+		}
+	}
+}
+
+func TestFileAccountRepositoryUpdateAccount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	repo, err := NewFileAccountRepository(path, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.AddAccount(Account{AccountNumber: "123456", Pin: "111111"})
+	err = repo.UpdateAccount("123456", func(account *Account) error {
+		account.Pin = "222222"
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	repo.Close()
+
+	reopened, err := NewFileAccountRepository(path, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer reopened.Close()
+	if acc := reopened.FindAccount("123456"); acc == nil || acc.Pin != "222222" {
+		t.Errorf("expected pin 222222 after replay, got %v", acc)
+	}
+}
+
+func TestFileAccountRepositoryPersistsUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	repo, err := NewFileAccountRepository(path, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.AddAccount(Account{AccountNumber: "123456", Balance: 1000})
+	repo.AddAccount(Account{AccountNumber: "654321"})
+	err = repo.UpdateAccounts([]string{"123456", "654321"}, func(accounts []*Account) error {
+		accounts[0].Balance -= 300
+		accounts[0].TransferUsage = Usage{Day: day, Amount: 300, Month: day, Count: 1}
+		accounts[1].Balance += 300
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	repo.Close()
+
+	reopened, err := NewFileAccountRepository(path, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer reopened.Close()
+	acc := reopened.FindAccount("123456")
+	if acc.Balance != 700 || !acc.TransferUsage.Day.Equal(day) || acc.TransferUsage.Amount != 300 || acc.TransferUsage.Count != 1 {
+		t.Errorf("expected balance and usage after replay, got %+v", acc)
+	}
+	if reopened.GetBalance("654321") != 300 {
+		t.Errorf("expected 300, got %d", reopened.GetBalance("654321"))
+	}
+}
@@ -0,0 +1,110 @@
+package account_repository
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// journalEntry records the state of every account touched by one mutation.
+// Entries carry absolute account states rather than deltas, so replaying an
+// entry twice can never credit or debit the same money twice.
+type journalEntry struct {
+	Seq      int64     `json:"seq"`
+	Accounts []Account `json:"accounts"`
+}
+
+// journalFile is the part of *os.File the journal writes through.
+type journalFile interface {
+	io.WriteCloser
+	Sync() error
+	Truncate(size int64) error
+}
+
+type journal struct {
+	path string
+	file journalFile
+	// size is where the next entry starts.
+	size int64
+}
+
+func openJournal(path string) (*journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &journal{path: path, file: file, size: info.Size()}, nil
+}
+
+// append writes entry and syncs it. When either step fails the journal is
+// cut back to where the entry started, so a half written entry never gets
+// glued to the next one and an entry the caller was told failed is never
+// replayed.
+func (j *journal) append(entry journalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := j.file.Write(data); err != nil {
+		j.file.Truncate(j.size)
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		j.file.Truncate(j.size)
+		return err
+	}
+	j.size += int64(len(data))
+	return nil
+}
+
+func (j *journal) truncate() error {
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	j.size = 0
+	return j.file.Sync()
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+// readJournal returns the complete entries in the journal at path. A torn
+// last line left behind by a crash mid-write is dropped; any other malformed
+// line is reported as corruption.
+func readJournal(path string) ([]journalEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			if line == bytes.Count(data, []byte{'\n'})+1 {
+				break
+			}
+			return nil, fmt.Errorf("corrupt journal %s at line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,28 @@
+package account_repository
+
+type AccountStore interface {
+	AddAccount(account Account) bool
+	FindAccount(number string) *Account
+	ListAccounts() []Account
+	GetBalance(number string) int
+	UpdateBalance(number string, balance int) bool
+	// UpdateAccount applies update to a copy of the account and stores the
+	// result unless update returns an error. The account number cannot change.
+	UpdateAccount(number string, update func(account *Account) error) error
+	// UpdateAccounts is UpdateAccount for several accounts as a single
+	// operation: update gets them in the order of numbers, and either every
+	// result is stored or none is.
+	UpdateAccounts(numbers []string, update func(accounts []*Account) error) error
+	// Withdraw and Deposit return the balance they left the account with.
+	Withdraw(number string, amount int) (int, bool)
+	Deposit(number string, amount int) (int, bool)
+	// Transfer moves amount between two accounts as a single operation:
+	// either both balances change or neither does. It returns the resulting
+	// source and destination balances.
+	Transfer(srcNumber, destNumber string, amount int) (int, int, bool)
+}
+
+var (
+	_ AccountStore = (*AccountRepository)(nil)
+	_ AccountStore = (*FileAccountRepository)(nil)
+)
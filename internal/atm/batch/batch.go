@@ -0,0 +1,372 @@
+package atm_batch
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	atm_service "atm-simulation-console/internal/atm/service"
+	interbank_switch "atm-simulation-console/internal/interbank/switch"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"atm-simulation-console/internal/util/generator"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidStep     = errors.New("invalid step")
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrNotLoggedIn     = errors.New("no account logged in")
+	ErrExpectedFailure = errors.New("step succeeded but was expected to fail")
+	ErrAccountExists   = errors.New("account already exists")
+	ErrAccountRefused  = errors.New("account could not be opened")
+	ErrNoTransfer      = errors.New("no interbank transfer sent since login")
+)
+
+// Result is written as one JSON line per step.
+type Result struct {
+	Line      int    `json:"line"`
+	Step      string `json:"step"`
+	Pass      bool   `json:"pass"`
+	Account   string `json:"account,omitempty"`
+	Balance   *int   `json:"balance,omitempty"`
+	Available *int   `json:"available,omitempty"`
+	Expected  *int   `json:"expected,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Summary struct {
+	Steps  int
+	Failed int
+}
+
+// step is one parsed scenario line. Every command may end in
+// "expect <balance>", "expect <transfer status>" or "expect fail".
+type step struct {
+	command      string
+	args         []string
+	expected     *int
+	expectStatus interbank_switch.Status
+	expectFail   bool
+}
+
+// outcome is what a command reports besides its error.
+type outcome struct {
+	reference string
+	status    interbank_switch.Status
+}
+
+// Runner plays scenario files against the service, one logged in account at
+// a time. It keeps its own clock, which only moves on a wait step, so
+// scenarios can check when interbank transfers settle.
+type Runner struct {
+	service   *atm_service.ATMService
+	account   string
+	interbank string
+	clock     time.Time
+	newRef    func() string
+}
+
+// NewRunner puts svc on the runner's clock. A switch that measures its own
+// settlement times should be put on it too, with Now.
+func NewRunner(svc *atm_service.ATMService) *Runner {
+	r := &Runner{
+		service: svc,
+		clock:   svc.Now(),
+		newRef: func() string {
+			return strconv.Itoa(generator.GenerateRandomNDigitNumber(6))
+		},
+	}
+	svc.SetClock(r.Now)
+	return r
+}
+
+// Now is the time on the runner's clock.
+func (r *Runner) Now() time.Time {
+	return r.clock
+}
+
+// Run executes the scenario read from in and writes a Result for every step
+// to w. Blank lines and lines starting with # are skipped. A failed step
+// does not stop the scenario.
+func (r *Runner) Run(in io.Reader, w io.Writer) (Summary, error) {
+	var summary Summary
+	enc := json.NewEncoder(w)
+
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		result := r.runStep(text)
+		result.Line = line
+		summary.Steps++
+		if !result.Pass {
+			summary.Failed++
+		}
+		if err := enc.Encode(result); err != nil {
+			return summary, err
+		}
+	}
+	return summary, scanner.Err()
+}
+
+func (r *Runner) runStep(text string) Result {
+	result := Result{Step: text}
+
+	st, err := parseStep(text)
+	var out outcome
+	if err == nil {
+		out, err = r.execute(st)
+	}
+
+	result.Account = r.account
+	result.Expected = st.expected
+	result.Reference = out.reference
+	result.Status = string(out.status)
+	if r.account != "" {
+		balance := r.service.GetBalance(r.account)
+		available := r.service.GetAvailableBalance(r.account)
+		result.Balance, result.Available = &balance, &available
+	}
+
+	// a mistyped step never counts as the expected failure
+	badStep := errors.Is(err, ErrInvalidStep) || errors.Is(err, ErrUnknownCommand)
+	switch {
+	case st.expectFail && err == nil:
+		err = ErrExpectedFailure
+	case st.expectFail && !badStep:
+		result.Pass = true
+		result.Error = err.Error()
+		return result
+	}
+	if err == nil && st.expected != nil {
+		if result.Balance == nil {
+			err = ErrNotLoggedIn
+		} else if *result.Balance != *st.expected {
+			err = fmt.Errorf("balance is %d, expected %d", *result.Balance, *st.expected)
+		}
+	}
+	if err == nil && st.expectStatus != "" && out.status != st.expectStatus {
+		if out.status == "" {
+			err = fmt.Errorf("%w: only interbank and status steps have a transfer status", ErrInvalidStep)
+		} else {
+			err = fmt.Errorf("status is %s, expected %s", out.status, st.expectStatus)
+		}
+	}
+
+	result.Pass = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func parseStep(text string) (step, error) {
+	fields := strings.Fields(text)
+	st := step{command: strings.ToLower(fields[0]), args: fields[1:]}
+
+	for i, arg := range st.args {
+		if !strings.EqualFold(arg, "expect") {
+			continue
+		}
+		if len(st.args) != i+2 {
+			return st, fmt.Errorf("%w: expect takes a balance, a transfer status or fail", ErrInvalidStep)
+		}
+		want := st.args[i+1]
+		switch status := interbank_switch.Status(strings.ToUpper(want)); {
+		case strings.EqualFold(want, "fail"):
+			st.expectFail = true
+		case status == interbank_switch.StatusPending || status == interbank_switch.StatusSettled || status == interbank_switch.StatusRejected:
+			st.expectStatus = status
+		default:
+			balance, err := strconv.Atoi(want)
+			if err != nil {
+				return st, fmt.Errorf("%w: expected balance %q is not a number", ErrInvalidStep, want)
+			}
+			st.expected = &balance
+		}
+		st.args = st.args[:i]
+		break
+	}
+	return st, nil
+}
+
+// execute runs one command and returns the reference number it used and
+// the interbank transfer status it saw, if any.
+func (r *Runner) execute(st step) (outcome, error) {
+	switch st.command {
+	case "account":
+		return outcome{}, r.openAccount(st.args)
+	case "login":
+		if len(st.args) != 2 {
+			return outcome{}, usage("login <account> <pin>")
+		}
+		return outcome{}, r.login(st.args[0], st.args[1])
+	case "logout", "exit":
+		r.account, r.interbank = "", ""
+		return outcome{}, nil
+	case "wait":
+		if len(st.args) != 1 {
+			return outcome{}, usage("wait <duration>")
+		}
+		d, err := time.ParseDuration(st.args[0])
+		if err != nil || d < 0 {
+			return outcome{}, usage("wait <duration>")
+		}
+		r.clock = r.clock.Add(d)
+		r.service.SettleInterbankTransfers()
+		return outcome{}, nil
+	}
+
+	if r.account == "" {
+		return outcome{}, ErrNotLoggedIn
+	}
+
+	switch st.command {
+	case "balance":
+		return outcome{}, nil
+	case "withdraw":
+		amount, err := amountArg(st.args, 1, "withdraw <amount>")
+		if err != nil {
+			return outcome{}, err
+		}
+		if err := r.service.ValidateOtherWithdraw(r.account, amount); err != nil {
+			return outcome{}, err
+		}
+		_, err = r.service.Withdraw(r.account, amount)
+		return outcome{}, err
+	case "deposit":
+		amount, err := amountArg(st.args, 1, "deposit <amount>")
+		if err != nil {
+			return outcome{}, err
+		}
+		ref := r.newRef()
+		return outcome{reference: ref}, r.service.Deposit(r.account, amount, ref)
+	case "transfer":
+		amount, err := amountArg(st.args, 3, "transfer <amount> to <account>")
+		if err != nil {
+			return outcome{}, err
+		}
+		if !strings.EqualFold(st.args[1], "to") {
+			return outcome{}, usage("transfer <amount> to <account>")
+		}
+		if _, err := r.service.ValidateTransferDestination(r.account, st.args[2]); err != nil {
+			return outcome{}, err
+		}
+		if err := r.service.ValidateTransferAmount(r.account, amount); err != nil {
+			return outcome{}, err
+		}
+		ref := r.newRef()
+		return outcome{reference: ref}, r.service.Transfer(r.account, st.args[2], amount, ref)
+	case "interbank":
+		amount, err := amountArg(st.args, 4, "interbank <amount> to <bank> <account>")
+		if err != nil {
+			return outcome{}, err
+		}
+		if !strings.EqualFold(st.args[1], "to") {
+			return outcome{}, usage("interbank <amount> to <bank> <account>")
+		}
+		ref := r.newRef()
+		status, err := r.service.InterbankTransfer(r.account, st.args[2], st.args[3], amount, ref)
+		if err == nil {
+			r.interbank = ref
+		}
+		return outcome{reference: ref, status: status}, err
+	case "status":
+		if len(st.args) != 0 {
+			return outcome{}, usage("status")
+		}
+		if r.interbank == "" {
+			return outcome{}, ErrNoTransfer
+		}
+		status, err := r.service.InterbankStatus(r.interbank)
+		if errors.Is(err, atm_service.ErrUnknownReference) {
+			// no longer pending, so the ledger entry has the outcome
+			status, err = r.settledStatus(r.interbank)
+		}
+		return outcome{reference: r.interbank, status: status}, err
+	case "change-pin":
+		if len(st.args) != 2 {
+			return outcome{}, usage("change-pin <current> <new>")
+		}
+		return outcome{}, r.service.ChangePIN(r.account, st.args[0], st.args[1], st.args[1])
+	}
+	return outcome{}, fmt.Errorf("%w: %s", ErrUnknownCommand, st.command)
+}
+
+// openAccount adds a test account: account <number> <pin> <balance> [name].
+// It never replaces an account that is already open.
+func (r *Runner) openAccount(args []string) error {
+	if len(args) < 3 {
+		return usage("account <number> <pin> <balance> [name]")
+	}
+	balance, err := strconv.Atoi(args[2])
+	if err != nil {
+		return usage("account <number> <pin> <balance> [name]")
+	}
+	if r.service.AccountExists(args[0]) {
+		return fmt.Errorf("%w: %s", ErrAccountExists, args[0])
+	}
+	if !r.service.AddAccount(account_repository.Account{
+		AccountNumber: args[0],
+		Name:          strings.Join(args[3:], " "),
+		Pin:           args[1],
+		Balance:       balance,
+	}) {
+		return fmt.Errorf("%w: %s", ErrAccountRefused, args[0])
+	}
+	return nil
+}
+
+// settledStatus reads the outcome of a transfer that is no longer pending
+// from its ledger entry.
+func (r *Runner) settledStatus(ref string) (interbank_switch.Status, error) {
+	for _, trx := range r.service.GetTransactions(r.account, time.Time{}, time.Time{}) {
+		if trx.Type != transaction_repository.TypeInterbankOut || trx.Reference != ref {
+			continue
+		}
+		switch trx.Status {
+		case transaction_repository.StatusSuccess:
+			return interbank_switch.StatusSettled, nil
+		case transaction_repository.StatusReversed:
+			return interbank_switch.StatusRejected, nil
+		}
+	}
+	return "", atm_service.ErrUnknownReference
+}
+
+func (r *Runner) login(accNumber, pin string) error {
+	r.account, r.interbank = "", ""
+	account, err := r.service.ValidateAccount(accNumber)
+	if account == nil {
+		return err
+	}
+	if _, err := r.service.ValidatePIN(account, pin); err != nil {
+		return err
+	}
+	r.account = accNumber
+	return nil
+}
+
+func amountArg(args []string, n int, syntax string) (int, error) {
+	if len(args) != n {
+		return 0, usage(syntax)
+	}
+	amount, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, usage(syntax)
+	}
+	return amount, nil
+}
+
+func usage(syntax string) error {
+	return fmt.Errorf("%w: usage: %s", ErrInvalidStep, syntax)
+}
@@ -0,0 +1,182 @@
+package atm_batch
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	atm_service "atm-simulation-console/internal/atm/service"
+	"atm-simulation-console/internal/config"
+	interbank_switch "atm-simulation-console/internal/interbank/switch"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestRunner() (*Runner, *account_repository.AccountRepository) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := atm_service.NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+	atmSvc.AddSampleAccounts()
+	return NewRunner(atmSvc), repo
+}
+
+func runScenario(t *testing.T, scenario string) ([]Result, Summary) {
+	t.Helper()
+	runner, _ := newTestRunner()
+
+	var out bytes.Buffer
+	summary, err := runner.Run(strings.NewReader(scenario), &out)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var results []Result
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var result Result
+		if err := dec.Decode(&result); err != nil {
+			t.Fatalf("decode result: %v", err)
+		}
+		results = append(results, result)
+	}
+	return results, summary
+}
+
+func TestRunScenario(t *testing.T) {
+	results, summary := runScenario(t, `# John moves money around
+login 112233 123123
+withdraw 50 expect 50
+
+transfer 20 to 112244 expect 30
+deposit 40 expect 70
+logout
+login 112244 123123
+balance expect 50
+exit
+`)
+
+	if summary.Steps != 8 || summary.Failed != 0 {
+		t.Fatalf("summary = %+v, want 8 steps and no failures: %+v", summary, results)
+	}
+	if results[1].Line != 3 || results[1].Account != "112233" || *results[1].Balance != 50 {
+		t.Errorf("withdraw result = %+v", results[1])
+	}
+	if results[2].Reference == "" {
+		t.Errorf("transfer has no reference")
+	}
+	if results[4].Balance != nil {
+		t.Errorf("logout still reports a balance: %+v", results[4])
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	tests := []struct {
+		step      string
+		wantPass  bool
+		wantError string
+	}{
+		{"withdraw 500", false, "insufficient balance"},
+		{"withdraw 500 expect fail", true, "insufficient balance"},
+		{"withdraw 10 expect fail", false, ErrExpectedFailure.Error()},
+		{"balance expect 1", false, "balance is 100, expected 1"},
+		{"transfer 5 112244", false, "usage: transfer <amount> to <account>"},
+		{"fly 10 expect fail", false, ErrUnknownCommand.Error()},
+		{"balance expect", false, ErrInvalidStep.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.step, func(t *testing.T) {
+			results, _ := runScenario(t, "login 112233 123123\n"+tt.step+"\n")
+			got := results[1]
+			if got.Pass != tt.wantPass {
+				t.Errorf("Pass = %v, want %v (%+v)", got.Pass, tt.wantPass, got)
+			}
+			if !strings.Contains(got.Error, tt.wantError) {
+				t.Errorf("Error = %q, want it to contain %q", got.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestStepsNeedLogin(t *testing.T) {
+	results, summary := runScenario(t, "withdraw 10\nlogin 112233 000000\nbalance\n")
+
+	if summary.Failed != 3 {
+		t.Fatalf("Failed = %d, want 3: %+v", summary.Failed, results)
+	}
+	if results[0].Error != ErrNotLoggedIn.Error() || results[2].Error != ErrNotLoggedIn.Error() {
+		t.Errorf("results = %+v, want not logged in errors", results)
+	}
+}
+
+func TestOpenAccount(t *testing.T) {
+	runner, repo := newTestRunner()
+
+	var out bytes.Buffer
+	summary, err := runner.Run(strings.NewReader("account 223344 482915 500 QA Tester\nlogin 223344 482915 expect 500\n"), &out)
+	if err != nil || summary.Failed != 0 {
+		t.Fatalf("Run() = %+v, %v\n%s", summary, err, out.String())
+	}
+	if acc := repo.FindAccount("223344"); acc == nil || acc.Name != "QA Tester" {
+		t.Errorf("account = %+v, want QA Tester", acc)
+	}
+}
+
+func TestOpenAccountRefusesExisting(t *testing.T) {
+	results, summary := runScenario(t, "account 112233 000000 9999 Mallory\nlogin 112233 123123 expect 100\n")
+
+	if summary.Failed != 1 || !strings.Contains(results[0].Error, ErrAccountExists.Error()) {
+		t.Fatalf("results = %+v, want the account step to fail with %v", results, ErrAccountExists)
+	}
+	if !results[1].Pass {
+		t.Errorf("existing account was changed: %+v", results[1])
+	}
+}
+
+func TestInterbankSettlesOnWait(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := atm_service.NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+	atmSvc.AddSampleAccounts()
+	sw := interbank_switch.NewSampleSwitch()
+	atmSvc.UseSwitch(sw)
+	runner := NewRunner(atmSvc)
+	sw.SetClock(runner.Now)
+
+	var out bytes.Buffer
+	summary, err := runner.Run(strings.NewReader(`login 112233 123123
+status expect fail
+interbank 30 to 008 223344 expect PENDING
+wait 5s
+status expect PENDING
+wait 5s
+status expect SETTLED
+balance expect 70
+interbank 20 to 014 556677
+wait 10s
+status expect REJECTED
+balance expect 70
+withdraw 10 expect PENDING
+`), &out)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var results []Result
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var result Result
+		if err := dec.Decode(&result); err != nil {
+			t.Fatalf("decode result: %v", err)
+		}
+		results = append(results, result)
+	}
+	if summary.Failed != 1 || results[12].Pass || !strings.Contains(results[12].Error, ErrInvalidStep.Error()) {
+		t.Fatalf("summary = %+v, want only the withdraw status check to fail: %+v", summary, results)
+	}
+	pending := results[2]
+	if pending.Status != "PENDING" || *pending.Balance != 100 || *pending.Available != 70 {
+		t.Errorf("interbank result = %+v, want PENDING with 70 available of 100", pending)
+	}
+	if results[6].Reference != pending.Reference {
+		t.Errorf("status reference = %q, want %q", results[6].Reference, pending.Reference)
+	}
+}
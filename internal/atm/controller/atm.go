@@ -2,12 +2,14 @@ package atm_controller
 
 import (
 	account_repository "atm-simulation-console/internal/account/repository"
+	atm_dispenser "atm-simulation-console/internal/atm/dispenser"
 	atm_service "atm-simulation-console/internal/atm/service"
 	"bufio"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"strconv"
-	"time"
+	"strings"
 
 	"atm-simulation-console/internal/util/formatter"
 	"atm-simulation-console/internal/util/generator"
@@ -18,353 +20,677 @@ type ATMData struct {
 	AccDest   string
 	Amount    string
 	Ref       string
+	// interbank transfers only
+	BankCode string
+	BankName string
+	DestName string
+}
+
+// session is what the screens of one card holder share.
+type session struct {
+	account *account_repository.Account
+	detail  ATMData
+	// withdrawals only
+	amount int
+	notes  []atm_dispenser.Note
+	// interbank transfers only
+	status string
+	// why the card is retained
+	retained error
+}
+
+// input remembers when the reader underneath has run out, so the controller
+// can stop instead of treating the end of a pipe as empty keystrokes. A read
+// may return the last bytes together with the error, so the input is only
+// closed once the lines buffered from it are used up as well.
+type input struct {
+	r       io.Reader
+	drained bool
+}
+
+func (in *input) Read(p []byte) (int, error) {
+	n, err := in.r.Read(p)
+	if err != nil {
+		in.drained = true
+	}
+	return n, err
 }
 
 type ATMController struct {
 	service *atm_service.ATMService
+	in      *input
+	reader  *bufio.Reader
+	out     io.Writer
+	// shutdown is set by the operator to stop serving customers.
+	shutdown bool
+	screens  map[state]func(s *session) event
+	// newRef numbers transfers and deposits
+	newRef func() string
 }
 
-func NewATMController(svc *atm_service.ATMService) *ATMController {
-	return &ATMController{
+// NewATMController reads the customer's input from in and writes every
+// screen to out.
+func NewATMController(svc *atm_service.ATMService, in io.Reader, out io.Writer) *ATMController {
+	c := &ATMController{
 		service: svc,
+		in:      &input{r: in},
+		out:     out,
+		newRef:  randomRef,
+	}
+	c.reader = bufio.NewReader(c.in)
+	c.screens = map[state]func(s *session) event{
+		stateAccountNumber:   c.displayAccountNumberScreen,
+		statePIN:             c.displayPINScreen,
+		stateCardRetained:    c.displayCardRetainedScreen,
+		stateMainMenu:        c.displayTrxScreen,
+		stateWithdrawMenu:    c.displayWithdrawScreen,
+		stateOtherWithdraw:   c.displayOtherWithdrawScreen,
+		stateWithdrawConfirm: c.displayWdConfirmScreen,
+		stateWithdrawSummary: c.displayWdSummaryScreen,
+		stateTransferDest:    c.displayTrfDestNumScreen,
+		stateTransferAmount:  c.displayTrfAmountScreen,
+		stateTransferConfirm: c.displayTransferConfirmScreen,
+		stateTransferSummary: c.displayTransferSummaryScreen,
+		stateMiniStatement:   c.displayMiniStatementScreen,
+		stateBalanceInquiry:  c.displayBalanceInquiryScreen,
+		stateDepositAmount:   c.displayDepositScreen,
+		stateDepositConfirm:  c.displayDepositConfirmScreen,
+		stateDepositSummary:  c.displayDepositSummaryScreen,
+		stateChangePIN:       c.displayChangePINScreen,
+
+		stateInterbankBank:    c.displayInterbankBankScreen,
+		stateInterbankDest:    c.displayInterbankDestScreen,
+		stateInterbankAmount:  c.displayInterbankAmountScreen,
+		stateInterbankConfirm: c.displayInterbankConfirmScreen,
+		stateInterbankSummary: c.displayInterbankSummaryScreen,
+
+		stateOperatorPIN:    c.displayOperatorPINScreen,
+		stateOperatorMenu:   c.displayOperatorScreen,
+		stateCassetteLevels: c.displayCassetteLevelsScreen,
+		stateAddCash:        c.displayAddCashScreen,
+		stateRemoveCash:     c.displayRemoveCashScreen,
+		stateTotalsReport:   c.displayTotalsReportScreen,
+		stateResetCounters:  c.displayResetCountersScreen,
+		stateUnblock:        c.displayUnblockScreen,
 	}
+	return c
 }
 
-func (c *ATMController) Start() {
-	c.initSampleAccounts()
-
-	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Print("enter Account Number: ")
-	accNumber := c.service.GetInputString(reader)
-
-	fmt.Print("enter PIN: ")
-	pin := c.service.GetInputString(reader)
+// closed reports whether the customer's input has run out.
+func (c *ATMController) closed() bool {
+	return c.in.drained && c.reader.Buffered() == 0
+}
 
-	account, err := c.service.ValidateAccount(accNumber)
-	if account == nil {
-		formatter.ErrorMessage(err.Error())
-		return
-	}
+// Start serves one customer after another, each with a fresh session, until
+// the operator shuts the machine down or the input runs out.
+func (c *ATMController) Start() {
+	c.service.AddSampleAccounts()
 
-	validated, err := c.service.ValidatePIN(account, pin)
-	if validated == nil {
-		formatter.ErrorMessage(err.Error())
-		return
+	for !c.shutdown && !c.closed() {
+		c.displayIdleScreen()
+		c.run(stateAccountNumber, &session{})
 	}
+}
 
-	for {
-		if !c.displayTrxScreen(reader, accNumber) {
-			break
+// run shows one screen per state until a transition leads to stateExit.
+// A screen only reports what happened; transitions decides what comes next.
+func (c *ATMController) run(current state, s *session) {
+	for current != stateExit && !c.closed() {
+		ev := c.screens[current](s)
+		next, ok := transitions[current][ev]
+		if !ok {
+			formatter.ErrorMessage(c.out, "unexpected "+string(ev)+" in "+string(current))
+			next = stateExit
 		}
+		current = next
 	}
 }
 
 // ==================================== PROCESSOR ====================================
 
-func (c *ATMController) processMainMenu(reader *bufio.Reader, accNumber string, option string) bool {
+func (c *ATMController) processMainMenu(option string) event {
 	switch option {
 	case "1":
-		return c.displayWithdrawScreen(reader, accNumber)
+		return eventWithdraw
 	case "2":
-		return c.displayTrfDestNumScreen(reader, accNumber)
+		return eventTransfer
 	case "3":
-	case "":
-		formatter.ErrorMessage("exiting...")
-		return false
+		return eventMiniStatement
+	case "4":
+		return eventBalance
+	case "5":
+		return eventDeposit
+	case "6":
+		return eventChangePIN
+	case "7":
+		return eventInterbank
+	case "8", "":
+		formatter.ErrorMessage(c.out, "exiting...")
+		return eventExit
 	default:
-		formatter.ErrorMessage("invalid option")
+		formatter.ErrorMessage(c.out, "invalid option")
 	}
 
-	return true
+	return eventMenu
 }
 
-func (c *ATMController) processWithdrawMenu(reader *bufio.Reader, accNumber string, option string) bool {
-	switch option {
-	case "1":
-		amount := 10
-		if ok := c.checkBalanceBoolResult(accNumber, amount); !ok {
-			return false
-		}
-		c.service.Withdraw(accNumber, amount)
-		return c.displayWdSummaryScreen(reader, accNumber, amount)
+func (c *ATMController) processWithdrawMenu(s *session, option string) event {
+	fastCash := c.service.Config().Withdraw.FastCash
+	otherOption := strconv.Itoa(len(fastCash) + 1)
+	backOption := strconv.Itoa(len(fastCash) + 2)
 
-	case "2":
-		amount := 50
-		if ok := c.checkBalanceBoolResult(accNumber, amount); !ok {
-			return false
-		}
-		c.service.Withdraw(accNumber, amount)
-		return c.displayWdSummaryScreen(reader, accNumber, amount)
-	case "3":
-		amount := 100
-		if ok := c.checkBalanceBoolResult(accNumber, amount); !ok {
-			return false
-		}
-		c.service.Withdraw(accNumber, amount)
-		return c.displayWdSummaryScreen(reader, accNumber, amount)
-	case "4":
-		return c.displayOtherWithdrawScreen(reader, accNumber)
-	case "5":
-	case "":
-		c.displayTrxScreen(reader, accNumber)
-		return false
+	switch option {
+	case otherOption:
+		return eventOther
+	case backOption, "":
 	default:
-		formatter.ErrorMessage("invalid option")
+		if i, err := strconv.Atoi(option); err == nil && i >= 1 && i <= len(fastCash) {
+			return c.processFastCash(s, fastCash[i-1])
+		}
+		formatter.ErrorMessage(c.out, "invalid option")
 	}
-	return true
+	return eventMenu
 }
 
-func (c *ATMController) processWdSummary(reader *bufio.Reader, accNumber string, option string) bool {
-	switch option {
-	case "1":
-		c.displayWithdrawScreen(reader, accNumber)
-	case "2":
-		return false
-	default:
-		formatter.ErrorMessage("invalid option")
+func (c *ATMController) processFastCash(s *session, amount int) event {
+	err := c.service.CheckBalance(s.detail.AccNumber, amount)
+	if err == nil {
+		err = c.service.CheckCash(amount)
 	}
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	s.amount = amount
+	return eventNext
+}
 
-	return true
+func (c *ATMController) processWithdraw(s *session) event {
+	notes, err := c.service.Withdraw(s.detail.AccNumber, s.amount)
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	s.notes = notes
+	return eventNext
 }
 
-func (c *ATMController) processTrfDestNumber(reader *bufio.Reader, accNumber string, val string) bool {
+func (c *ATMController) processTrfDestNumber(s *session, val string) event {
 	switch val {
-	case "":
-	case "0":
-		c.displayTrxScreen(reader, accNumber)
-		return false
-	default:
-		account, err := c.service.ValidateAccount(val)
-		if account == nil {
-			formatter.ErrorMessage(err.Error())
-			return true
-		}
-		detail := ATMData{
-			AccNumber: accNumber,
-			AccDest:   val,
-		}
-		return c.displayTrfAmountScreen(reader, detail)
+	case "", "0":
+		return eventMenu
+	}
+
+	account, err := c.service.ValidateTransferDestination(s.detail.AccNumber, val)
+	if account == nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
 	}
-	return true
+	s.detail.AccDest = val
+	return eventNext
 }
 
-func (c *ATMController) processTrfAmount(reader *bufio.Reader, detail ATMData) bool {
-	switch detail.Amount {
-	case "":
-	case "0":
-		c.displayTrxScreen(reader, detail.AccNumber)
-		return false
-	default:
-		intAmount, err := strconv.Atoi(detail.Amount)
-		if err != nil {
-			formatter.ErrorMessage("invalid amount")
-		}
-		err = c.service.ValidateTransferAmount(detail.AccNumber, intAmount)
+func (c *ATMController) processTrfAmount(s *session) event {
+	switch s.detail.Amount {
+	case "", "0":
+		return eventMenu
+	}
+
+	intAmount, err := strconv.Atoi(s.detail.Amount)
+	if err != nil {
+		formatter.ErrorMessage(c.out, "invalid amount")
+		return eventMenu
+	}
+	err = c.service.ValidateTransferAmount(s.detail.AccNumber, intAmount)
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	return eventNext
+}
+
+func (c *ATMController) processTrfConfirm(s *session, option string) event {
+	switch option {
+	case "1":
+		intAmount, _ := strconv.Atoi(s.detail.Amount)
+		err := c.service.Transfer(s.detail.AccNumber, s.detail.AccDest, intAmount, s.detail.Ref)
 		if err != nil {
-			formatter.ErrorMessage(err.Error())
-			return true
+			formatter.ErrorMessage(c.out, err.Error())
+			return eventMenu
 		}
-		return c.displayTransferConfirmScreen(reader, detail)
+		return eventNext
+	case "2", "":
+	default:
+		formatter.ErrorMessage(c.out, "invalid option")
 	}
-	return true
+
+	return eventMenu
 }
 
-func (c *ATMController) processTrfConfirm(reader *bufio.Reader, detail ATMData, option string) bool {
+func (c *ATMController) processDepositAmount(s *session) event {
+	switch s.detail.Amount {
+	case "", "0":
+		return eventMenu
+	}
+
+	intAmount, err := strconv.Atoi(s.detail.Amount)
+	if err != nil {
+		formatter.ErrorMessage(c.out, "invalid amount")
+		return eventMenu
+	}
+	err = c.service.ValidateDeposit(intAmount)
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	return eventNext
+}
+
+func (c *ATMController) processDepositConfirm(s *session, option string) event {
 	switch option {
 	case "1":
-		intAmount, _ := strconv.Atoi(detail.Amount)
-		err := c.service.Transfer(detail.AccNumber, detail.AccDest, intAmount)
+		intAmount, _ := strconv.Atoi(s.detail.Amount)
+		err := c.service.Deposit(s.detail.AccNumber, intAmount, s.detail.Ref)
 		if err != nil {
-			formatter.ErrorMessage(err.Error())
-			return true
+			formatter.ErrorMessage(c.out, err.Error())
+			return eventMenu
 		}
-		c.displayTransferSummaryScreen(reader, detail)
-	case "2":
-		return c.displayTrxScreen(reader, detail.AccNumber)
+		return eventNext
+	case "2", "":
 	default:
-		formatter.ErrorMessage("invalid option")
+		formatter.ErrorMessage(c.out, "invalid option")
 	}
 
-	return true
+	return eventMenu
 }
 
-func (c *ATMController) processTrxSummary(reader *bufio.Reader, accNumber string, option string) bool {
+func (c *ATMController) processTrxSummary(option string) event {
 	switch option {
 	case "1":
-		c.displayTrxScreen(reader, accNumber)
-	case "2":
-		return false
+		return eventMenu
+	case "2", "":
+		return eventExit
 	default:
-		formatter.ErrorMessage("invalid option")
+		formatter.ErrorMessage(c.out, "invalid option")
 	}
 
-	return true
+	return eventMenu
 }
 
 // ==================================== DISPLAY SCREEN ====================================
 
-func (c *ATMController) displayTrxScreen(reader *bufio.Reader, accNumber string) bool {
-	fmt.Println("1. Withdraw")
-	fmt.Println("2. Fund Transfer")
-	fmt.Println("3. Exit")
-	fmt.Print("Please choose option[3]: ")
+func (c *ATMController) displayIdleScreen() {
+	if greeting := c.service.Config().Greeting; greeting != "" {
+		fmt.Fprintln(c.out, "")
+		fmt.Fprintln(c.out, greeting)
+	}
+}
+
+func (c *ATMController) displayAccountNumberScreen(s *session) event {
+	fmt.Fprint(c.out, "enter Account Number: ")
+	accNumber := c.service.GetInputString(c.reader)
+	if accNumber == "" && c.closed() {
+		return eventExit
+	}
+
+	if c.service.IsOperatorCard(accNumber) {
+		return eventOperator
+	}
+
+	account, err := c.service.ValidateAccount(accNumber)
+	if account == nil {
+		var statusErr *atm_service.AccountStatusError
+		if errors.As(err, &statusErr) {
+			s.retained = err
+			return eventBlocked
+		}
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventExit
+	}
+
+	s.account = account
+	s.detail = ATMData{AccNumber: accNumber}
+	return eventNext
+}
+
+func (c *ATMController) displayPINScreen(s *session) event {
+	fmt.Fprint(c.out, "enter PIN: ")
+	pin := c.service.GetInputString(c.reader)
+	if pin == "" && c.closed() {
+		// the customer walked away; that is not a wrong PIN
+		return eventExit
+	}
 
-	option := c.service.GetInputString(reader)
-	return c.processMainMenu(reader, accNumber, option)
+	validated, err := c.service.ValidatePIN(s.account, pin)
+	if validated != nil {
+		return eventNext
+	}
+	formatter.ErrorMessage(c.out, err.Error())
+	if errors.Is(err, atm_service.ErrAccountBlocked) {
+		s.retained = err
+		return eventBlocked
+	}
+	return eventRetry
 }
 
-func (c *ATMController) displayWithdrawScreen(reader *bufio.Reader, accNumber string) bool {
-	fmt.Println("1. $10")
-	fmt.Println("2. $50")
-	fmt.Println("3. $100")
-	fmt.Println("4. Other")
-	fmt.Println("5. Back")
-	fmt.Print("Please choose option[5]: ")
+func (c *ATMController) displayTrxScreen(s *session) event {
+	// every flow starts over from the menu
+	s.detail = ATMData{AccNumber: s.detail.AccNumber}
+	s.amount, s.notes, s.status = 0, nil, ""
+
+	fmt.Fprintln(c.out, "1. Withdraw")
+	fmt.Fprintln(c.out, "2. Fund Transfer")
+	fmt.Fprintln(c.out, "3. Mini Statement")
+	fmt.Fprintln(c.out, "4. Balance Inquiry")
+	fmt.Fprintln(c.out, "5. Deposit")
+	fmt.Fprintln(c.out, "6. Change PIN")
+	fmt.Fprintln(c.out, "7. Interbank Transfer")
+	fmt.Fprintln(c.out, "8. Exit")
+	fmt.Fprint(c.out, "Please choose option[8]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processMainMenu(option)
+}
+
+func (c *ATMController) displayWithdrawScreen(s *session) event {
+	if c.service.OutOfCash() {
+		formatter.ErrorMessage(c.out, "Sorry, this ATM is out of cash")
+		return eventMenu
+	}
+
+	fastCash := c.service.Config().Withdraw.FastCash
+	for i, amount := range fastCash {
+		fmt.Fprintf(c.out, "%d. %s\n", i+1, formatter.CurrencyFormatter(amount))
+	}
+	fmt.Fprintf(c.out, "%d. Other\n", len(fastCash)+1)
+	fmt.Fprintf(c.out, "%d. Back\n", len(fastCash)+2)
+	fmt.Fprintf(c.out, "Please choose option[%d]: ", len(fastCash)+2)
 
-	option := c.service.GetInputString(reader)
-	return c.processWithdrawMenu(reader, accNumber, option)
+	option := c.service.GetInputString(c.reader)
+	return c.processWithdrawMenu(s, option)
 }
 
-func (c *ATMController) displayOtherWithdrawScreen(reader *bufio.Reader, accNumber string) bool {
-	fmt.Println("Other Withdraw")
-	fmt.Print("Enter amount to withdraw: ")
+func (c *ATMController) displayOtherWithdrawScreen(s *session) event {
+	fmt.Fprintln(c.out, "Other Withdraw")
+	fmt.Fprint(c.out, "Enter amount to withdraw: ")
 
-	amount, err := c.service.GetInputNumber(reader)
+	amount, err := c.service.GetInputNumber(c.reader)
 	if err != nil {
-		formatter.ErrorMessage(err.Error())
-		return true
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
 	}
 
-	err = c.service.ValidateOtherWithdraw(accNumber, amount)
+	err = c.service.ValidateOtherWithdraw(s.detail.AccNumber, amount)
 	if err != nil {
-		formatter.ErrorMessage(err.Error())
-		return true
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	s.amount = amount
+	return eventNext
+}
+
+// displayWdConfirmScreen asks the customer to accept the fee before a
+// withdrawal. Free withdrawals go ahead without asking.
+func (c *ATMController) displayWdConfirmScreen(s *session) event {
+	fee := c.service.Fee(s.detail.AccNumber, atm_service.ChannelWithdraw, s.amount)
+	if fee == 0 {
+		return c.processWithdraw(s)
 	}
 
-	c.service.Withdraw(accNumber, amount)
-	return c.displayWdSummaryScreen(reader, accNumber, amount)
+	fmt.Fprintln(c.out, "Withdraw Confirmation")
+	fmt.Fprintln(c.out, "Withdraw Amount     : "+formatter.CurrencyFormatter(s.amount))
+	fmt.Fprintln(c.out, "Fee                 : "+formatter.CurrencyFormatter(fee))
+	fmt.Fprintln(c.out, "Total Debit         : "+formatter.CurrencyFormatter(s.amount+fee))
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Confirm Trx")
+	fmt.Fprintln(c.out, "2. Cancel Trx")
+	fmt.Fprint(c.out, "Choose option[2]: ")
+
+	if c.service.GetInputString(c.reader) != "1" {
+		return eventMenu
+	}
+	return c.processWithdraw(s)
 }
 
-func (c *ATMController) displayWdSummaryScreen(reader *bufio.Reader, accNumber string, amount int) bool {
+func (c *ATMController) displayWdSummaryScreen(s *session) event {
+
+	time := formatter.DateFormatter(c.service.Now())
+	balance := c.service.GetBalance(s.detail.AccNumber)
+	remaining := c.service.RemainingDailyLimit(s.detail.AccNumber, atm_service.ChannelWithdraw)
+
+	fmt.Fprintln(c.out, "Summary")
+	fmt.Fprintln(c.out, "Date		: "+time)
+	fmt.Fprintln(c.out, "Withdraw	: "+strconv.Itoa(s.amount))
+	if len(s.notes) > 0 {
+		fmt.Fprintln(c.out, "Notes		: "+formatNotes(s.notes))
+	}
+	fmt.Fprintln(c.out, "Balance	: "+strconv.Itoa(balance))
+	fmt.Fprintln(c.out, "Limit Left	: "+formatter.CurrencyFormatter(remaining))
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Transaction")
+	fmt.Fprintln(c.out, "2. Exit")
+	fmt.Fprint(c.out, "Choose option[2]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processTrxSummary(option)
+}
 
-	time := formatter.DateFormatter(time.Now())
-	balance := c.service.GetBalance(accNumber)
+func (c *ATMController) displayTrfDestNumScreen(s *session) event {
 
-	fmt.Println("Summary")
-	fmt.Println("Date		: " + time)
-	fmt.Println("Withdraw	: " + strconv.Itoa(amount))
-	fmt.Println("Balance	: " + strconv.Itoa(balance))
-	fmt.Println("")
-	fmt.Println("1. Transaction")
-	fmt.Println("2. Exit")
-	fmt.Print("Choose option[2]: ")
+	fmt.Fprintln(c.out, "Please enter destination account")
+	fmt.Fprintln(c.out, "or enter 0 to go back to Transaction")
+	fmt.Fprint(c.out, "Destination account[0]: ")
 
-	option := c.service.GetInputString(reader)
-	return c.processWdSummary(reader, accNumber, option)
+	accDest := c.service.GetInputString(c.reader)
+	return c.processTrfDestNumber(s, accDest)
 }
 
-func (c *ATMController) displayTrfDestNumScreen(reader *bufio.Reader, accNumber string) bool {
+func (c *ATMController) displayTrfAmountScreen(s *session) event {
 
-	fmt.Println("Please enter destination account")
-	fmt.Println("or enter 0 to go back to Transaction")
-	fmt.Print("Destination account[0]: ")
+	fmt.Fprintln(c.out, "Please enter transfer amount")
+	fmt.Fprintln(c.out, "or enter 0 to go back to Transaction")
+	fmt.Fprint(c.out, "Transfer amount[0]: ")
 
-	accDest := c.service.GetInputString(reader)
-	if accDest != "" {
-		return c.processTrfDestNumber(reader, accNumber, accDest)
+	s.detail.Amount = c.service.GetInputString(c.reader)
+	return c.processTrfAmount(s)
+}
+
+func (c *ATMController) displayTransferConfirmScreen(s *session) event {
+
+	s.detail.Ref = c.newRef()
+
+	fmt.Fprintln(c.out, "Transfer Confirmation")
+	fmt.Fprintln(c.out, "Destination Account : "+s.detail.AccDest)
+	fmt.Fprintln(c.out, "Transfer Amount     : "+s.detail.Amount)
+	if amount, err := strconv.Atoi(s.detail.Amount); err == nil {
+		if fee := c.service.Fee(s.detail.AccNumber, atm_service.ChannelTransfer, amount); fee > 0 {
+			fmt.Fprintln(c.out, "Fee                 : "+formatter.CurrencyFormatter(fee))
+			fmt.Fprintln(c.out, "Total Debit         : "+formatter.CurrencyFormatter(amount+fee))
+		}
 	}
+	fmt.Fprintln(c.out, "Reference Number    : "+s.detail.Ref)
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Confirm Trx")
+	fmt.Fprintln(c.out, "2. Cancel Trx")
+	fmt.Fprint(c.out, "Choose option[2]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processTrfConfirm(s, option)
+}
+
+func (c *ATMController) displayTransferSummaryScreen(s *session) event {
+
+	balance := c.service.GetBalance(s.detail.AccNumber)
+	remaining := c.service.RemainingDailyLimit(s.detail.AccNumber, atm_service.ChannelTransfer)
+
+	fmt.Fprintln(c.out, "Fund Transfer Summary")
+	fmt.Fprintln(c.out, "Destination Account : "+s.detail.AccDest)
+	fmt.Fprintln(c.out, "Transfer Amount     : "+s.detail.Amount)
+	fmt.Fprintln(c.out, "Reference Number    : "+s.detail.Ref)
+	fmt.Fprintln(c.out, "Balance             : "+strconv.Itoa(balance))
+	fmt.Fprintln(c.out, "Daily Limit Left    : "+formatter.CurrencyFormatter(remaining))
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Transaction")
+	fmt.Fprintln(c.out, "2. Exit")
+	fmt.Fprint(c.out, "Choose option[2]: ")
 
-	c.displayTrxScreen(reader, accNumber)
-	return false
+	option := c.service.GetInputString(c.reader)
+	return c.processTrxSummary(option)
 }
 
-func (c *ATMController) displayTrfAmountScreen(reader *bufio.Reader, detail ATMData) bool {
+func (c *ATMController) displayMiniStatementScreen(s *session) event {
+
+	transactions := c.service.MiniStatement(s.detail.AccNumber, c.service.Config().MiniStatementSize)
+
+	fmt.Fprintln(c.out, "Mini Statement")
+	if len(transactions) == 0 {
+		fmt.Fprintln(c.out, "No transactions yet")
+	} else {
+		fmt.Fprintf(c.out, "%-19s  %-12s  %8s  %8s\n", "Date", "Type", "Amount", "Balance")
+		for _, trx := range transactions {
+			fmt.Fprintf(c.out, "%-19s  %-12s  %8s  %8s\n",
+				formatter.DateFormatter(trx.Timestamp),
+				trx.Type,
+				formatter.CurrencyFormatter(trx.Amount),
+				formatter.CurrencyFormatter(trx.Balance))
+		}
+	}
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Transaction")
+	fmt.Fprintln(c.out, "2. Exit")
+	fmt.Fprint(c.out, "Choose option[2]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processTrxSummary(option)
+}
 
-	fmt.Println("Please enter transfer amount")
-	fmt.Println("or enter 0 to go back to Transaction")
-	fmt.Print("Transfer amount[0]: ")
+func (c *ATMController) displayBalanceInquiryScreen(s *session) event {
 
-	amount := c.service.GetInputString(reader)
-	detail.Amount = amount
-	if detail.AccDest != "" {
-		return c.processTrfAmount(reader, detail)
+	inquiry, err := c.service.BalanceInquiry(s.detail.AccNumber)
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
 	}
 
-	c.displayTrxScreen(reader, detail.AccNumber)
-	return false
+	fmt.Fprintln(c.out, "Balance Inquiry")
+	fmt.Fprintln(c.out, "Account Name        : "+inquiry.Name)
+	fmt.Fprintln(c.out, "Available Balance   : "+formatter.CurrencyFormatter(inquiry.AvailableBalance))
+	fmt.Fprintln(c.out, "Ledger Balance      : "+formatter.CurrencyFormatter(inquiry.LedgerBalance))
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Transaction")
+	fmt.Fprintln(c.out, "2. Exit")
+	fmt.Fprint(c.out, "Choose option[2]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processTrxSummary(option)
 }
 
-func (c *ATMController) displayTransferConfirmScreen(reader *bufio.Reader, detail ATMData) bool {
+func (c *ATMController) displayDepositScreen(s *session) event {
+
+	fmt.Fprintln(c.out, "Please enter deposit amount (multiple of "+formatter.CurrencyFormatter(c.service.Config().NoteMultiple)+")")
+	fmt.Fprintln(c.out, "or enter 0 to go back to Transaction")
+	fmt.Fprint(c.out, "Deposit amount[0]: ")
 
-	refNum := generator.GenerateRandomNDigitNumber(6)
-	stringRef := strconv.Itoa(refNum)
+	s.detail.Amount = c.service.GetInputString(c.reader)
+	return c.processDepositAmount(s)
+}
 
-	fmt.Println("Transfer Confirmation")
-	fmt.Println("Destination Account : " + detail.AccDest)
-	fmt.Println("Transfer Amount     : " + detail.Amount)
-	fmt.Println("Reference Number    : " + stringRef)
-	fmt.Println("")
-	fmt.Println("1. Confirm Trx")
-	fmt.Println("2. Cancel Trx")
-	fmt.Print("Choose option[2]: ")
+func (c *ATMController) displayDepositConfirmScreen(s *session) event {
 
-	option := c.service.GetInputString(reader)
-	details := ATMData{
-		AccNumber: detail.AccNumber,
-		AccDest:   detail.AccDest,
-		Amount:    detail.Amount,
-		Ref:       stringRef,
-	}
-	return c.processTrfConfirm(reader, details, option)
+	s.detail.Ref = c.newRef()
+
+	fmt.Fprintln(c.out, "Deposit Confirmation")
+	fmt.Fprintln(c.out, "Deposit Amount      : "+s.detail.Amount)
+	fmt.Fprintln(c.out, "Reference Number    : "+s.detail.Ref)
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Confirm Trx")
+	fmt.Fprintln(c.out, "2. Cancel Trx")
+	fmt.Fprint(c.out, "Choose option[2]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processDepositConfirm(s, option)
 }
 
-func (c *ATMController) displayTransferSummaryScreen(reader *bufio.Reader, detail ATMData) bool {
+func (c *ATMController) displayDepositSummaryScreen(s *session) event {
 
-	balance := c.service.GetBalance(detail.AccNumber)
+	time := formatter.DateFormatter(c.service.Now())
+	balance := c.service.GetBalance(s.detail.AccNumber)
 
-	fmt.Println("Fund Transfer Summary")
-	fmt.Println("Destination Account : " + detail.AccDest)
-	fmt.Println("Transfer Amount     : " + detail.Amount)
-	fmt.Println("Reference Number    : " + detail.Ref)
-	fmt.Println("Balance             : " + strconv.Itoa(balance))
-	fmt.Println("")
-	fmt.Println("1. Transaction")
-	fmt.Println("2. Exit")
-	fmt.Print("Choose option[2]: ")
+	fmt.Fprintln(c.out, "Summary")
+	fmt.Fprintln(c.out, "Date		: "+time)
+	fmt.Fprintln(c.out, "Deposit		: "+s.detail.Amount)
+	fmt.Fprintln(c.out, "Reference	: "+s.detail.Ref)
+	fmt.Fprintln(c.out, "Balance	: "+strconv.Itoa(balance))
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Transaction")
+	fmt.Fprintln(c.out, "2. Exit")
+	fmt.Fprint(c.out, "Choose option[2]: ")
 
-	option := c.service.GetInputString(reader)
-	return c.processTrxSummary(reader, detail.AccNumber, option)
+	option := c.service.GetInputString(c.reader)
+	return c.processTrxSummary(option)
 }
 
-// ==================================== OTHER ====================================
+func (c *ATMController) displayChangePINScreen(s *session) event {
+
+	fmt.Fprintln(c.out, "Change PIN")
+	fmt.Fprint(c.out, "Enter current PIN: ")
+	currentPin := c.service.GetInputString(c.reader)
+	fmt.Fprint(c.out, "Enter new PIN: ")
+	newPin := c.service.GetInputString(c.reader)
+	fmt.Fprint(c.out, "Re-enter new PIN: ")
+	confirmPin := c.service.GetInputString(c.reader)
 
-func (c *ATMController) checkBalanceBoolResult(accNumber string, amount int) bool {
-	err := c.service.CheckBalance(accNumber, amount)
+	err := c.service.ChangePIN(s.detail.AccNumber, currentPin, newPin, confirmPin)
 	if err != nil {
-		formatter.ErrorMessage(err.Error())
-		return false
+		formatter.ErrorMessage(c.out, err.Error())
+		if errors.Is(err, atm_service.ErrAccountBlocked) {
+			s.retained = err
+			return eventBlocked
+		}
+		return eventMenu
 	}
-	return true
-}
 
-// ==================================== ACCOUNT SEEDER ====================================
+	fmt.Fprintln(c.out, "PIN changed successfully")
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Transaction")
+	fmt.Fprintln(c.out, "2. Exit")
+	fmt.Fprint(c.out, "Choose option[2]: ")
 
-func (c *ATMController) initSampleAccounts() {
-	// Add sample accounts
-	account1 := account_repository.Account{
-		AccountNumber: "112233",
-		Name:          "John Doe",
-		Pin:           "123123",
-		Balance:       100,
-	}
-	account2 := account_repository.Account{
-		AccountNumber: "112244",
-		Name:          "Jane Doe",
-		Pin:           "123123",
-		Balance:       30,
+	option := c.service.GetInputString(c.reader)
+	return c.processTrxSummary(option)
+}
+
+func (c *ATMController) displayCardRetainedScreen(s *session) event {
+	fmt.Fprintln(c.out, "==========================================")
+	fmt.Fprintln(c.out, "Card retained")
+	var statusErr *atm_service.AccountStatusError
+	switch {
+	case errors.Is(s.retained, atm_service.ErrOperatorLocked):
+		fmt.Fprintln(c.out, "The operator card has been locked after too")
+		fmt.Fprintln(c.out, "many wrong PIN attempts.")
+	case !errors.As(s.retained, &statusErr):
+		fmt.Fprintln(c.out, "Please contact your bank.")
+	case statusErr.Status == account_repository.StatusBlocked && statusErr.Reason != "":
+		fmt.Fprintln(c.out, "Your account has been blocked after too many")
+		fmt.Fprintln(c.out, "wrong PIN attempts. Please contact your bank.")
+	default:
+		fmt.Fprintln(c.out, "Your account is "+string(statusErr.Status)+".")
+		fmt.Fprintln(c.out, "Please contact your bank.")
 	}
+	fmt.Fprintln(c.out, "==========================================")
+	return eventExit
+}
+
+// ==================================== OTHER ====================================
+
+func randomRef() string {
+	return strconv.Itoa(generator.GenerateRandomNDigitNumber(6))
+}
 
-	c.service.AddAccount(account1)
-	c.service.AddAccount(account2)
+func formatNotes(notes []atm_dispenser.Note) string {
+	parts := make([]string, 0, len(notes))
+	for _, note := range notes {
+		parts = append(parts, strconv.Itoa(note.Count)+" x "+formatter.CurrencyFormatter(note.Denomination))
+	}
+	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,127 @@
+package atm_controller
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	atm_service "atm-simulation-console/internal/atm/service"
+	"atm-simulation-console/internal/config"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStartWithInjectedStreams(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := atm_service.NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	// log in, take $10 fast cash, then exit from the summary
+	in := strings.NewReader("112233\n123123\n1\n1\n2\n")
+	var out bytes.Buffer
+	NewATMController(atmSvc, in, &out).Start()
+
+	if balance := repo.GetBalance("112233"); balance != 90 {
+		t.Errorf("balance = %d, want 90", balance)
+	}
+	for _, want := range []string{
+		"enter Account Number: ",
+		"Please choose option[8]: ",
+		"Withdraw	: 10",
+		"Notes		: 1 x $10",
+		"Balance	: 90",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestInputEndingWithItsLastBytes(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := atm_service.NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	// the reader returns the whole session together with io.EOF
+	in := iotest.DataErrReader(strings.NewReader("112233\n123123\n1\n1\n2\n"))
+	var out bytes.Buffer
+	NewATMController(atmSvc, in, &out).Start()
+
+	if balance := repo.GetBalance("112233"); balance != 90 {
+		t.Errorf("balance = %d, want 90:\n%s", balance, out.String())
+	}
+}
+
+func TestErrorsGoToInjectedWriter(t *testing.T) {
+	atmSvc := atm_service.NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), config.Default())
+
+	var out bytes.Buffer
+	NewATMController(atmSvc, strings.NewReader("000000\n"), &out).Start()
+
+	if !strings.Contains(out.String(), "==========================================\n") {
+		t.Errorf("error message not written to output:\n%s", out.String())
+	}
+}
+
+func TestEndOfInputIsNotAWrongPIN(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := atm_service.NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	var out bytes.Buffer
+	NewATMController(atmSvc, strings.NewReader("112233\n"), &out).Start()
+
+	if acc := repo.FindAccount("112233"); acc.FailedPINAttempts != 0 {
+		t.Errorf("FailedPINAttempts = %d, want 0", acc.FailedPINAttempts)
+	}
+	if strings.Contains(out.String(), "invalid") {
+		t.Errorf("end of input reported as an error:\n%s", out.String())
+	}
+}
+
+func TestCardRetainedShowsStatus(t *testing.T) {
+	tests := []struct {
+		status account_repository.AccountStatus
+		want   string
+	}{
+		{status: account_repository.StatusBlocked, want: "Your account is blocked."},
+		{status: account_repository.StatusDormant, want: "Your account is dormant."},
+		{status: account_repository.StatusClosed, want: "Your account is closed."},
+	}
+	for _, test := range tests {
+		atmSvc := atm_service.NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), config.Default())
+		atmSvc.AddSampleAccounts()
+		if err := atmSvc.SetAccountStatus("112233", test.status); err != nil {
+			t.Fatalf("SetAccountStatus(%s): %v", test.status, err)
+		}
+
+		var out bytes.Buffer
+		NewATMController(atmSvc, strings.NewReader("112233\n"), &out).Start()
+
+		if !strings.Contains(out.String(), "Card retained\n"+test.want) {
+			t.Errorf("%s: output is missing %q:\n%s", test.status, test.want, out.String())
+		}
+		if strings.Contains(out.String(), "wrong PIN attempts") {
+			t.Errorf("%s: blamed on wrong PINs:\n%s", test.status, out.String())
+		}
+	}
+}
+
+func TestFastCashChecksCassettesBeforeConfirming(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cassettes = []config.CassetteConfig{{Denomination: 20, Count: 10}}
+	cfg.Fees.Withdraw = config.FeeConfig{Fixed: 2}
+	repo := account_repository.NewAccountRepository()
+	atmSvc := atm_service.NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	// $10 fast cash cannot be paid with $20 notes
+	var out bytes.Buffer
+	NewATMController(atmSvc, strings.NewReader("112233\n123123\n1\n1\n"), &out).Start()
+
+	if strings.Contains(out.String(), "Withdraw Confirmation") {
+		t.Errorf("confirmation shown for an amount the cassettes cannot pay:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "cannot dispense $10") {
+		t.Errorf("output is missing the dispense error:\n%s", out.String())
+	}
+	if balance := repo.GetBalance("112233"); balance != 100 {
+		t.Errorf("balance = %d, want 100", balance)
+	}
+}
@@ -0,0 +1,133 @@
+package atm_controller
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	atm_service "atm-simulation-console/internal/atm/service"
+	"atm-simulation-console/internal/config"
+	interbank_switch "atm-simulation-console/internal/interbank/switch"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden transcripts in testdata")
+
+// TestGoldenTranscripts runs every testdata/*.keys file through a full
+// session and compares the screens with the matching .golden file. Each line
+// of a .keys file is one line of input; lines starting with # are comments.
+// Run with -update to accept the new output.
+func TestGoldenTranscripts(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("testdata", "*.keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no transcripts in testdata")
+	}
+
+	for _, script := range scripts {
+		name := strings.TrimSuffix(filepath.Base(script), ".keys")
+		t.Run(name, func(t *testing.T) {
+			keys, err := os.ReadFile(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := runTranscript(keys)
+
+			golden := strings.TrimSuffix(script, ".keys") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("transcript differs from %s (run go test -update to accept):\n%s", golden, firstDiff(want, got))
+			}
+		})
+	}
+}
+
+// runTranscript plays keys against a fresh ATM with a fixed clock and
+// sequential reference numbers, so the transcript is the same on every run.
+func runTranscript(keys []byte) []byte {
+	clock := func() time.Time { return time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC) }
+
+	cfg := config.Default()
+	cfg.Operator = config.OperatorConfig{Card: "999999", PIN: "909090"}
+	atmSvc := atm_service.NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), cfg)
+	atmSvc.SetClock(clock)
+	sw := interbank_switch.NewSampleSwitch()
+	sw.SetClock(clock)
+	atmSvc.UseSwitch(sw)
+
+	var out bytes.Buffer
+	c := NewATMController(atmSvc, &echoReader{lines: keyLines(keys), out: &out}, &out)
+	ref := 100000
+	c.newRef = func() string {
+		ref++
+		return strconv.Itoa(ref)
+	}
+	c.Start()
+	return out.Bytes()
+}
+
+func keyLines(keys []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(keys), "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line+"\n")
+	}
+	return lines
+}
+
+// echoReader hands out one line per Read and writes it to out as well, so
+// the transcript shows what was typed at each prompt.
+type echoReader struct {
+	lines []string
+	out   io.Writer
+}
+
+func (r *echoReader) Read(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.lines[0])
+	r.out.Write(p[:n])
+	r.lines[0] = r.lines[0][n:]
+	if r.lines[0] == "" {
+		r.lines = r.lines[1:]
+	}
+	return n, nil
+}
+
+func firstDiff(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return "line " + strconv.Itoa(i+1) + ":\nwant: " + strconv.Quote(w) + "\ngot:  " + strconv.Quote(g)
+		}
+	}
+	return ""
+}
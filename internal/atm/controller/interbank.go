@@ -0,0 +1,157 @@
+package atm_controller
+
+import (
+	atm_service "atm-simulation-console/internal/atm/service"
+	"atm-simulation-console/internal/util/formatter"
+	"fmt"
+	"strconv"
+)
+
+// ==================================== PROCESSOR ====================================
+
+func (c *ATMController) processInterbankBank(s *session, option string) event {
+	banks := c.service.Banks()
+	i, err := strconv.Atoi(option)
+	switch {
+	case option == "" || i == len(banks)+1:
+		return eventMenu
+	case err != nil || i < 1 || i > len(banks):
+		formatter.ErrorMessage(c.out, "invalid option")
+		return eventMenu
+	}
+
+	s.detail.BankCode = banks[i-1].Code
+	s.detail.BankName = banks[i-1].Name
+	return eventNext
+}
+
+func (c *ATMController) processInterbankDest(s *session) event {
+	if s.detail.AccDest == "" || s.detail.AccDest == "0" {
+		return eventMenu
+	}
+
+	name, err := c.service.InterbankNameInquiry(s.detail.BankCode, s.detail.AccDest)
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	s.detail.DestName = name
+	return eventNext
+}
+
+func (c *ATMController) processInterbankAmount(s *session) event {
+	if s.detail.Amount == "" || s.detail.Amount == "0" {
+		return eventMenu
+	}
+
+	intAmount, err := strconv.Atoi(s.detail.Amount)
+	if err != nil {
+		formatter.ErrorMessage(c.out, "invalid amount")
+		return eventMenu
+	}
+	if err := c.service.ValidateTransferAmount(s.detail.AccNumber, intAmount); err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	return eventNext
+}
+
+func (c *ATMController) processInterbankConfirm(s *session, option string) event {
+	if option != "1" {
+		return eventMenu
+	}
+
+	intAmount, _ := strconv.Atoi(s.detail.Amount)
+	status, err := c.service.InterbankTransfer(s.detail.AccNumber, s.detail.BankCode, s.detail.AccDest, intAmount, s.detail.Ref)
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	s.status = string(status)
+	return eventNext
+}
+
+// ==================================== DISPLAY SCREEN ====================================
+
+func (c *ATMController) displayInterbankBankScreen(s *session) event {
+	banks := c.service.Banks()
+	if len(banks) == 0 {
+		formatter.ErrorMessage(c.out, atm_service.ErrNoSwitch.Error())
+		return eventMenu
+	}
+
+	fmt.Fprintln(c.out, "Interbank Transfer")
+	for i, bank := range banks {
+		fmt.Fprintf(c.out, "%d. %s %s\n", i+1, bank.Code, bank.Name)
+	}
+	fmt.Fprintf(c.out, "%d. Back\n", len(banks)+1)
+	fmt.Fprintf(c.out, "Please choose bank[%d]: ", len(banks)+1)
+
+	option := c.service.GetInputString(c.reader)
+	return c.processInterbankBank(s, option)
+}
+
+func (c *ATMController) displayInterbankDestScreen(s *session) event {
+	fmt.Fprintln(c.out, "Please enter destination account at "+s.detail.BankName)
+	fmt.Fprintln(c.out, "or enter 0 to go back to Transaction")
+	fmt.Fprint(c.out, "Destination account[0]: ")
+
+	s.detail.AccDest = c.service.GetInputString(c.reader)
+	return c.processInterbankDest(s)
+}
+
+func (c *ATMController) displayInterbankAmountScreen(s *session) event {
+	fmt.Fprintln(c.out, "Account Name        : "+s.detail.DestName)
+	fmt.Fprintln(c.out, "Please enter transfer amount")
+	fmt.Fprintln(c.out, "or enter 0 to go back to Transaction")
+	fmt.Fprint(c.out, "Transfer amount[0]: ")
+
+	s.detail.Amount = c.service.GetInputString(c.reader)
+	return c.processInterbankAmount(s)
+}
+
+func (c *ATMController) displayInterbankConfirmScreen(s *session) event {
+	s.detail.Ref = c.newRef()
+	amount, _ := strconv.Atoi(s.detail.Amount)
+	fee := c.service.Fee(s.detail.AccNumber, atm_service.ChannelTransfer, amount)
+
+	fmt.Fprintln(c.out, "Interbank Transfer Confirmation")
+	fmt.Fprintln(c.out, "Bank                : "+s.detail.BankCode+" "+s.detail.BankName)
+	fmt.Fprintln(c.out, "Destination Account : "+s.detail.AccDest)
+	fmt.Fprintln(c.out, "Account Name        : "+s.detail.DestName)
+	fmt.Fprintln(c.out, "Transfer Amount     : "+s.detail.Amount)
+	if fee > 0 {
+		fmt.Fprintln(c.out, "Fee                 : "+formatter.CurrencyFormatter(fee))
+		fmt.Fprintln(c.out, "Total Debit         : "+formatter.CurrencyFormatter(amount+fee))
+	}
+	fmt.Fprintln(c.out, "Reference Number    : "+s.detail.Ref)
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Confirm Trx")
+	fmt.Fprintln(c.out, "2. Cancel Trx")
+	fmt.Fprint(c.out, "Choose option[2]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processInterbankConfirm(s, option)
+}
+
+func (c *ATMController) displayInterbankSummaryScreen(s *session) event {
+	available := c.service.GetAvailableBalance(s.detail.AccNumber)
+	remaining := c.service.RemainingDailyLimit(s.detail.AccNumber, atm_service.ChannelTransfer)
+
+	fmt.Fprintln(c.out, "Interbank Transfer Summary")
+	fmt.Fprintln(c.out, "Bank                : "+s.detail.BankCode+" "+s.detail.BankName)
+	fmt.Fprintln(c.out, "Destination Account : "+s.detail.AccDest)
+	fmt.Fprintln(c.out, "Account Name        : "+s.detail.DestName)
+	fmt.Fprintln(c.out, "Transfer Amount     : "+s.detail.Amount)
+	fmt.Fprintln(c.out, "Reference Number    : "+s.detail.Ref)
+	fmt.Fprintln(c.out, "Status              : "+s.status)
+	fmt.Fprintln(c.out, "Available Balance   : "+formatter.CurrencyFormatter(available))
+	fmt.Fprintln(c.out, "Daily Limit Left    : "+formatter.CurrencyFormatter(remaining))
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "1. Transaction")
+	fmt.Fprintln(c.out, "2. Exit")
+	fmt.Fprint(c.out, "Choose option[2]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processTrxSummary(option)
+}
@@ -0,0 +1,185 @@
+package atm_controller
+
+import (
+	atm_service "atm-simulation-console/internal/atm/service"
+	"atm-simulation-console/internal/util/formatter"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// StartMaintenance opens maintenance mode without the operator card, for
+// staff at the console.
+func (c *ATMController) StartMaintenance() {
+	c.run(stateOperatorMenu, &session{})
+}
+
+// ==================================== PROCESSOR ====================================
+
+func (c *ATMController) processOperatorMenu(option string) event {
+	switch option {
+	case "1":
+		return eventCassettes
+	case "2":
+		return eventAddCash
+	case "3":
+		return eventRemoveCash
+	case "4":
+		return eventTotals
+	case "5":
+		return eventReset
+	case "6":
+		return eventUnblock
+	case "7", "":
+		formatter.ErrorMessage(c.out, "leaving maintenance mode...")
+		return eventExit
+	case "8":
+		formatter.ErrorMessage(c.out, "shutting down...")
+		c.shutdown = true
+		return eventExit
+	default:
+		formatter.ErrorMessage(c.out, "invalid option")
+	}
+	return eventMenu
+}
+
+func (c *ATMController) processCash(load bool) {
+	fmt.Fprint(c.out, "Enter denomination: ")
+	denomination, err := c.service.GetInputNumber(c.reader)
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return
+	}
+	fmt.Fprint(c.out, "Enter number of notes: ")
+	count, err := c.service.GetInputNumber(c.reader)
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return
+	}
+
+	notes := strconv.Itoa(count) + " x " + formatter.CurrencyFormatter(denomination)
+	if load {
+		err = c.service.LoadCash(denomination, count)
+		notes = "loaded " + notes
+	} else {
+		err = c.service.RemoveCash(denomination, count)
+		notes = "removed " + notes
+	}
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return
+	}
+	formatter.ErrorMessage(c.out, notes)
+}
+
+// ==================================== DISPLAY ====================================
+
+func (c *ATMController) displayOperatorPINScreen(s *session) event {
+	fmt.Fprint(c.out, "enter operator PIN: ")
+	pin := c.service.GetInputString(c.reader)
+	if pin == "" && c.closed() {
+		return eventExit
+	}
+
+	err := c.service.VerifyOperatorPIN(pin)
+	if err == nil {
+		return eventNext
+	}
+	formatter.ErrorMessage(c.out, err.Error())
+	if errors.Is(err, atm_service.ErrOperatorLocked) {
+		s.retained = err
+		return eventBlocked
+	}
+	return eventRetry
+}
+
+func (c *ATMController) displayOperatorScreen(s *session) event {
+	fmt.Fprintln(c.out, "Maintenance Mode")
+	fmt.Fprintln(c.out, "1. Cassette Levels")
+	fmt.Fprintln(c.out, "2. Add Cash")
+	fmt.Fprintln(c.out, "3. Remove Cash")
+	fmt.Fprintln(c.out, "4. Totals Report")
+	fmt.Fprintln(c.out, "5. Reset Counters")
+	fmt.Fprintln(c.out, "6. Unblock Account")
+	fmt.Fprintln(c.out, "7. Exit")
+	fmt.Fprintln(c.out, "8. Shut Down")
+	fmt.Fprint(c.out, "Please choose option[7]: ")
+
+	option := c.service.GetInputString(c.reader)
+	return c.processOperatorMenu(option)
+}
+
+func (c *ATMController) displayCassetteLevelsScreen(s *session) event {
+	cassettes, err := c.service.CassetteReport()
+	if err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+
+	total := 0
+	fmt.Fprintln(c.out, "Cassette Levels")
+	for _, cassette := range cassettes {
+		fmt.Fprintf(c.out, "%-8s: %d notes\n", formatter.CurrencyFormatter(cassette.Denomination), cassette.Count)
+		total += cassette.Denomination * cassette.Count
+	}
+	fmt.Fprintln(c.out, "Total   : "+formatter.CurrencyFormatter(total))
+	fmt.Fprintln(c.out, "")
+	return eventMenu
+}
+
+func (c *ATMController) displayAddCashScreen(s *session) event {
+	fmt.Fprintln(c.out, "Add Cash")
+	c.processCash(true)
+	return eventMenu
+}
+
+func (c *ATMController) displayRemoveCashScreen(s *session) event {
+	fmt.Fprintln(c.out, "Remove Cash")
+	c.processCash(false)
+	return eventMenu
+}
+
+func (c *ATMController) displayTotalsReportScreen(s *session) event {
+	report := c.service.TotalsReport()
+
+	fmt.Fprintln(c.out, "Totals Report")
+	fmt.Fprintln(c.out, "Since               : "+formatter.DateFormatter(report.Since))
+	fmt.Fprintf(c.out, "Withdrawals         : %d (%s)\n", report.Withdrawals, formatter.CurrencyFormatter(report.Withdrawn))
+	fmt.Fprintf(c.out, "Deposits            : %d (%s)\n", report.Deposits, formatter.CurrencyFormatter(report.Deposited))
+	fmt.Fprintf(c.out, "Transfers           : %d (%s)\n", report.Transfers, formatter.CurrencyFormatter(report.Transferred))
+	fmt.Fprintln(c.out, "Fees                : "+formatter.CurrencyFormatter(report.Fees))
+	fmt.Fprintf(c.out, "Failed              : %d\n", report.Failed)
+	if len(report.Cassettes) > 0 {
+		fmt.Fprintln(c.out, "")
+		fmt.Fprintf(c.out, "%-8s  %6s  %9s  %6s  %7s\n", "Note", "Level", "Dispensed", "Loaded", "Removed")
+		for _, cassette := range report.Cassettes {
+			fmt.Fprintf(c.out, "%-8s  %6d  %9d  %6d  %7d\n",
+				formatter.CurrencyFormatter(cassette.Denomination),
+				cassette.Count,
+				cassette.Dispensed,
+				cassette.Loaded,
+				cassette.Removed)
+		}
+		fmt.Fprintln(c.out, "Cash Left           : "+formatter.CurrencyFormatter(report.CashLeft))
+	}
+	fmt.Fprintln(c.out, "")
+	return eventMenu
+}
+
+func (c *ATMController) displayResetCountersScreen(s *session) event {
+	c.service.ResetCounters()
+	formatter.ErrorMessage(c.out, "counters reset")
+	return eventMenu
+}
+
+func (c *ATMController) displayUnblockScreen(s *session) event {
+	fmt.Fprint(c.out, "Enter account number to unblock: ")
+	accNumber := c.service.GetInputString(c.reader)
+
+	if err := c.service.UnblockAccount(accNumber); err != nil {
+		formatter.ErrorMessage(c.out, err.Error())
+		return eventMenu
+	}
+	formatter.ErrorMessage(c.out, "account "+accNumber+" unblocked")
+	return eventMenu
+}
@@ -0,0 +1,199 @@
+package atm_controller
+
+// state is a screen of the ATM. A state's screen runs once per visit and
+// reports what happened as an event; transitions picks the next state from
+// the pair.
+type state string
+
+const (
+	stateAccountNumber   state = "account_number"
+	statePIN             state = "pin"
+	stateCardRetained    state = "card_retained"
+	stateMainMenu        state = "main_menu"
+	stateWithdrawMenu    state = "withdraw_menu"
+	stateOtherWithdraw   state = "other_withdraw"
+	stateWithdrawConfirm state = "withdraw_confirm"
+	stateWithdrawSummary state = "withdraw_summary"
+	stateTransferDest    state = "transfer_dest"
+	stateTransferAmount  state = "transfer_amount"
+	stateTransferConfirm state = "transfer_confirm"
+	stateTransferSummary state = "transfer_summary"
+	stateMiniStatement   state = "mini_statement"
+	stateBalanceInquiry  state = "balance_inquiry"
+	stateDepositAmount   state = "deposit_amount"
+	stateDepositConfirm  state = "deposit_confirm"
+	stateDepositSummary  state = "deposit_summary"
+	stateChangePIN       state = "change_pin"
+
+	stateInterbankBank    state = "interbank_bank"
+	stateInterbankDest    state = "interbank_dest"
+	stateInterbankAmount  state = "interbank_amount"
+	stateInterbankConfirm state = "interbank_confirm"
+	stateInterbankSummary state = "interbank_summary"
+
+	stateOperatorPIN    state = "operator_pin"
+	stateOperatorMenu   state = "operator_menu"
+	stateCassetteLevels state = "cassette_levels"
+	stateAddCash        state = "add_cash"
+	stateRemoveCash     state = "remove_cash"
+	stateTotalsReport   state = "totals_report"
+	stateResetCounters  state = "reset_counters"
+	stateUnblock        state = "unblock"
+
+	// stateExit ends the session. It has no screen.
+	stateExit state = "exit"
+)
+
+type event string
+
+const (
+	// eventNext moves on to the next step of the current flow.
+	eventNext event = "next"
+	// eventMenu goes back to the menu, after "Back", "Cancel", "Transaction"
+	// or an error message.
+	eventMenu     event = "menu"
+	eventExit     event = "exit"
+	eventRetry    event = "retry"
+	eventBlocked  event = "blocked"
+	eventOperator event = "operator"
+
+	eventWithdraw      event = "withdraw"
+	eventOther         event = "other"
+	eventTransfer      event = "transfer"
+	eventMiniStatement event = "mini_statement"
+	eventBalance       event = "balance"
+	eventDeposit       event = "deposit"
+	eventChangePIN     event = "change_pin"
+	eventInterbank     event = "interbank"
+
+	eventCassettes  event = "cassettes"
+	eventAddCash    event = "add_cash"
+	eventRemoveCash event = "remove_cash"
+	eventTotals     event = "totals"
+	eventReset      event = "reset"
+	eventUnblock    event = "unblock"
+)
+
+// summary screens offer "1. Transaction" and "2. Exit"
+var summaryTransitions = map[event]state{
+	eventMenu: stateMainMenu,
+	eventExit: stateExit,
+}
+
+var transitions = map[state]map[event]state{
+	stateAccountNumber: {
+		eventNext:     statePIN,
+		eventOperator: stateOperatorPIN,
+		eventBlocked:  stateCardRetained,
+		eventExit:     stateExit,
+	},
+	statePIN: {
+		eventNext:    stateMainMenu,
+		eventRetry:   statePIN,
+		eventBlocked: stateCardRetained,
+		eventExit:    stateExit,
+	},
+	stateCardRetained: {
+		eventExit: stateExit,
+	},
+	stateMainMenu: {
+		eventWithdraw:      stateWithdrawMenu,
+		eventTransfer:      stateTransferDest,
+		eventMiniStatement: stateMiniStatement,
+		eventBalance:       stateBalanceInquiry,
+		eventDeposit:       stateDepositAmount,
+		eventChangePIN:     stateChangePIN,
+		eventInterbank:     stateInterbankBank,
+		eventMenu:          stateMainMenu,
+		eventExit:          stateExit,
+	},
+
+	stateWithdrawMenu: {
+		eventNext:  stateWithdrawConfirm,
+		eventOther: stateOtherWithdraw,
+		eventMenu:  stateMainMenu,
+	},
+	stateOtherWithdraw: {
+		eventNext: stateWithdrawConfirm,
+		eventMenu: stateMainMenu,
+	},
+	stateWithdrawConfirm: {
+		eventNext: stateWithdrawSummary,
+		eventMenu: stateMainMenu,
+	},
+	stateWithdrawSummary: summaryTransitions,
+
+	stateTransferDest: {
+		eventNext: stateTransferAmount,
+		eventMenu: stateMainMenu,
+	},
+	stateTransferAmount: {
+		eventNext: stateTransferConfirm,
+		eventMenu: stateMainMenu,
+	},
+	stateTransferConfirm: {
+		eventNext: stateTransferSummary,
+		eventMenu: stateMainMenu,
+	},
+	stateTransferSummary: summaryTransitions,
+
+	stateMiniStatement:  summaryTransitions,
+	stateBalanceInquiry: summaryTransitions,
+
+	stateDepositAmount: {
+		eventNext: stateDepositConfirm,
+		eventMenu: stateMainMenu,
+	},
+	stateDepositConfirm: {
+		eventNext: stateDepositSummary,
+		eventMenu: stateMainMenu,
+	},
+	stateDepositSummary: summaryTransitions,
+
+	stateChangePIN: {
+		eventMenu:    stateMainMenu,
+		eventExit:    stateExit,
+		eventBlocked: stateCardRetained,
+	},
+
+	stateInterbankBank: {
+		eventNext: stateInterbankDest,
+		eventMenu: stateMainMenu,
+	},
+	stateInterbankDest: {
+		eventNext: stateInterbankAmount,
+		eventMenu: stateMainMenu,
+	},
+	stateInterbankAmount: {
+		eventNext: stateInterbankConfirm,
+		eventMenu: stateMainMenu,
+	},
+	stateInterbankConfirm: {
+		eventNext: stateInterbankSummary,
+		eventMenu: stateMainMenu,
+	},
+	stateInterbankSummary: summaryTransitions,
+
+	stateOperatorPIN: {
+		eventNext:    stateOperatorMenu,
+		eventRetry:   stateOperatorPIN,
+		eventBlocked: stateCardRetained,
+		eventExit:    stateExit,
+	},
+	stateOperatorMenu: {
+		eventCassettes:  stateCassetteLevels,
+		eventAddCash:    stateAddCash,
+		eventRemoveCash: stateRemoveCash,
+		eventTotals:     stateTotalsReport,
+		eventReset:      stateResetCounters,
+		eventUnblock:    stateUnblock,
+		eventMenu:       stateOperatorMenu,
+		eventExit:       stateExit,
+	},
+	stateCassetteLevels: {eventMenu: stateOperatorMenu},
+	stateAddCash:        {eventMenu: stateOperatorMenu},
+	stateRemoveCash:     {eventMenu: stateOperatorMenu},
+	stateTotalsReport:   {eventMenu: stateOperatorMenu},
+	stateResetCounters:  {eventMenu: stateOperatorMenu},
+	stateUnblock:        {eventMenu: stateOperatorMenu},
+}
@@ -0,0 +1,30 @@
+package atm_controller
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTransitionsHaveScreens(t *testing.T) {
+	c := NewATMController(nil, strings.NewReader(""), io.Discard)
+
+	for from, events := range transitions {
+		if _, ok := c.screens[from]; !ok {
+			t.Errorf("state %s has transitions but no screen", from)
+		}
+		for ev, to := range events {
+			if to == stateExit {
+				continue
+			}
+			if _, ok := transitions[to]; !ok {
+				t.Errorf("%s on %s leads to %s, which has no transitions", from, ev, to)
+			}
+		}
+	}
+	for st := range c.screens {
+		if _, ok := transitions[st]; !ok {
+			t.Errorf("screen %s is never left", st)
+		}
+	}
+}
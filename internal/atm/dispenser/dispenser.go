@@ -0,0 +1,299 @@
+package atm_dispenser
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrOutOfCash       = errors.New("ATM is out of cash")
+	ErrCannotDispense  = errors.New("cannot dispense amount")
+	ErrUnknownCassette = errors.New("no cassette for that denomination")
+	ErrInvalidCount    = errors.New("invalid note count")
+)
+
+// CannotDispenseError reports an amount the notes left in the cassettes
+// cannot make exactly.
+type CannotDispenseError struct {
+	Amount int
+}
+
+func (e *CannotDispenseError) Error() string {
+	return "cannot dispense $" + strconv.Itoa(e.Amount) + " with the notes available"
+}
+
+func (e *CannotDispenseError) Is(target error) bool {
+	return target == ErrCannotDispense
+}
+
+type Cassette struct {
+	Denomination int `json:"denomination"`
+	Count        int `json:"count"`
+}
+
+// Note is one line of a dispense breakdown: Count notes of Denomination.
+type Note struct {
+	Denomination int
+	Count        int
+}
+
+// CassetteReport is a cassette's level together with the notes that went in
+// and out of it since the counters were last reset.
+type CassetteReport struct {
+	Denomination int
+	Count        int
+	Dispensed    int
+	Loaded       int
+	Removed      int
+}
+
+type counters struct {
+	dispensed int
+	loaded    int
+	removed   int
+}
+
+type Dispenser struct {
+	mu        sync.Mutex
+	cassettes []Cassette
+	counters  map[int]*counters
+}
+
+// NewDispenser loads the cassettes, largest denomination first. Cassettes
+// sharing a denomination are merged.
+func NewDispenser(cassettes []Cassette) *Dispenser {
+	byDenomination := make(map[int]int)
+	for _, c := range cassettes {
+		byDenomination[c.Denomination] += c.Count
+	}
+
+	d := &Dispenser{counters: make(map[int]*counters)}
+	for denomination, count := range byDenomination {
+		d.cassettes = append(d.cassettes, Cassette{Denomination: denomination, Count: count})
+		d.counters[denomination] = &counters{}
+	}
+	sort.Slice(d.cassettes, func(i, j int) bool {
+		return d.cassettes[i].Denomination > d.cassettes[j].Denomination
+	})
+	return d
+}
+
+// Total returns the cash left in the machine.
+func (d *Dispenser) Total() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return total(d.cassettes)
+}
+
+func (d *Dispenser) OutOfCash() bool {
+	return d.Total() == 0
+}
+
+// Plan returns the note mix Dispense would pay out for amount without taking
+// the notes out of the cassettes.
+func (d *Dispenser) Plan(amount int) ([]Note, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.plan(amount)
+}
+
+// Dispense takes the notes for amount out of the cassettes and returns the
+// breakdown, largest denomination first.
+func (d *Dispenser) Dispense(amount int) ([]Note, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	notes, err := d.plan(amount)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		d.add(note.Denomination, -note.Count)
+		d.counters[note.Denomination].dispensed += note.Count
+	}
+	return notes, nil
+}
+
+// Return puts notes back into the cassettes, e.g. when the debit behind a
+// dispense fails.
+func (d *Dispenser) Return(notes []Note) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, note := range notes {
+		d.add(note.Denomination, note.Count)
+		d.counters[note.Denomination].dispensed -= note.Count
+	}
+}
+
+// Load adds notes to the cassette holding denomination.
+func (d *Dispenser) Load(denomination, count int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.checkCassette(denomination, count); err != nil {
+		return err
+	}
+	d.add(denomination, count)
+	d.counters[denomination].loaded += count
+	return nil
+}
+
+// Remove takes notes out of the cassette holding denomination. It cannot take
+// more than the cassette holds.
+func (d *Dispenser) Remove(denomination, count int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.checkCassette(denomination, count); err != nil {
+		return err
+	}
+	for _, c := range d.cassettes {
+		if c.Denomination == denomination && c.Count < count {
+			return ErrInvalidCount
+		}
+	}
+	d.add(denomination, -count)
+	d.counters[denomination].removed += count
+	return nil
+}
+
+// Report returns every cassette with its counters, largest denomination
+// first.
+func (d *Dispenser) Report() []CassetteReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	report := make([]CassetteReport, 0, len(d.cassettes))
+	for _, c := range d.cassettes {
+		counter := d.counters[c.Denomination]
+		report = append(report, CassetteReport{
+			Denomination: c.Denomination,
+			Count:        c.Count,
+			Dispensed:    counter.dispensed,
+			Loaded:       counter.loaded,
+			Removed:      counter.removed,
+		})
+	}
+	return report
+}
+
+// ResetCounters zeroes the dispensed, loaded and removed counters, e.g. after
+// the machine has been balanced.
+func (d *Dispenser) ResetCounters() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, counter := range d.counters {
+		*counter = counters{}
+	}
+}
+
+func (d *Dispenser) checkCassette(denomination, count int) error {
+	if count <= 0 {
+		return ErrInvalidCount
+	}
+	if _, ok := d.counters[denomination]; !ok {
+		return ErrUnknownCassette
+	}
+	return nil
+}
+
+func (d *Dispenser) add(denomination, count int) {
+	for i := range d.cassettes {
+		if d.cassettes[i].Denomination == denomination {
+			d.cassettes[i].Count += count
+			return
+		}
+	}
+}
+
+// plan finds the mix with the fewest notes using a bounded change-making
+// table. Amounts are scaled down by the greatest common divisor of the
+// denominations to keep the table small.
+func (d *Dispenser) plan(amount int) ([]Note, error) {
+	if total(d.cassettes) == 0 {
+		return nil, ErrOutOfCash
+	}
+	if amount <= 0 || amount > total(d.cassettes) {
+		return nil, &CannotDispenseError{Amount: amount}
+	}
+
+	unit := 0
+	for _, c := range d.cassettes {
+		if c.Count > 0 {
+			unit = gcd(unit, c.Denomination)
+		}
+	}
+	if amount%unit != 0 {
+		return nil, &CannotDispenseError{Amount: amount}
+	}
+	target := amount / unit
+
+	const unreachable = -1
+	// best[a] is the fewest notes making a units with the cassettes seen so
+	// far; used[i][a] is how many notes cassette i contributes to that mix.
+	best := make([]int, target+1)
+	for a := 1; a <= target; a++ {
+		best[a] = unreachable
+	}
+	used := make([][]int, len(d.cassettes))
+
+	for i, c := range d.cassettes {
+		used[i] = make([]int, target+1)
+		if c.Count == 0 {
+			continue
+		}
+		value := c.Denomination / unit
+		next := make([]int, target+1)
+		for a := 0; a <= target; a++ {
+			next[a] = best[a]
+			for k := 1; k <= c.Count && k*value <= a; k++ {
+				prev := best[a-k*value]
+				if prev == unreachable {
+					continue
+				}
+				if next[a] == unreachable || prev+k < next[a] {
+					next[a] = prev + k
+					used[i][a] = k
+				}
+			}
+		}
+		best = next
+	}
+
+	if best[target] == unreachable {
+		return nil, &CannotDispenseError{Amount: amount}
+	}
+
+	var notes []Note
+	remaining := target
+	for i := len(d.cassettes) - 1; i >= 0; i-- {
+		k := used[i][remaining]
+		if k == 0 {
+			continue
+		}
+		notes = append([]Note{{Denomination: d.cassettes[i].Denomination, Count: k}}, notes...)
+		remaining -= k * d.cassettes[i].Denomination / unit
+	}
+	return notes, nil
+}
+
+func total(cassettes []Cassette) int {
+	sum := 0
+	for _, c := range cassettes {
+		sum += c.Denomination * c.Count
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,133 @@
+package atm_dispenser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDispense(t *testing.T) {
+	tests := []struct {
+		name      string
+		cassettes []Cassette
+		amount    int
+		expected  []Note
+	}{
+		{"largest notes first", []Cassette{{10, 10}, {20, 10}, {50, 10}, {100, 10}}, 180, []Note{{100, 1}, {50, 1}, {20, 1}, {10, 1}}},
+		{"greedy would fail", []Cassette{{50, 5}, {20, 5}}, 60, []Note{{20, 3}}},
+		{"limited large notes", []Cassette{{100, 1}, {50, 2}, {10, 10}}, 280, []Note{{100, 1}, {50, 2}, {10, 8}}},
+		{"fewest notes beats greedy", []Cassette{{40, 5}, {30, 5}, {10, 5}}, 60, []Note{{30, 2}}},
+		{"merged cassettes", []Cassette{{20, 1}, {20, 1}}, 40, []Note{{20, 2}}},
+	}
+
+	for _, test := range tests {
+		d := NewDispenser(test.cassettes)
+		before := d.Total()
+
+		notes, err := d.Dispense(test.amount)
+		if err != nil {
+			t.Fatalf("%s: Expected no error, got %v", test.name, err)
+		}
+		if !reflect.DeepEqual(notes, test.expected) {
+			t.Errorf("%s: Expected %v, got %v", test.name, test.expected, notes)
+		}
+		if d.Total() != before-test.amount {
+			t.Errorf("%s: Expected %d left, got %d", test.name, before-test.amount, d.Total())
+		}
+	}
+}
+
+func TestDispenseErrors(t *testing.T) {
+	d := NewDispenser([]Cassette{{50, 2}, {20, 1}})
+
+	for _, amount := range []int{0, 30, 10, 80, 130, 200} {
+		if _, err := d.Dispense(amount); !errors.Is(err, ErrCannotDispense) {
+			t.Errorf("amount %d: Expected ErrCannotDispense, got %v", amount, err)
+		}
+	}
+	if d.Total() != 120 {
+		t.Errorf("Expected failed dispenses to leave $120, got %d", d.Total())
+	}
+
+	if _, err := d.Dispense(120); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !d.OutOfCash() {
+		t.Error("Expected dispenser to be out of cash")
+	}
+	if _, err := d.Dispense(20); !errors.Is(err, ErrOutOfCash) {
+		t.Errorf("Expected ErrOutOfCash, got %v", err)
+	}
+}
+
+func TestPlanAndReturn(t *testing.T) {
+	d := NewDispenser([]Cassette{{10, 5}, {50, 2}})
+
+	notes, err := d.Plan(70)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if d.Total() != 150 {
+		t.Errorf("Expected Plan to leave cassettes untouched, got %d", d.Total())
+	}
+
+	notes, _ = d.Dispense(70)
+	d.Return(notes)
+	expected := []CassetteReport{{Denomination: 50, Count: 2}, {Denomination: 10, Count: 5}}
+	if !reflect.DeepEqual(d.Report(), expected) {
+		t.Errorf("Expected %v after return, got %v", expected, d.Report())
+	}
+}
+
+func TestLoadRemoveAndReport(t *testing.T) {
+	d := NewDispenser([]Cassette{{50, 2}, {20, 5}})
+
+	if err := d.Load(50, 10); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := d.Remove(20, 3); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := d.Dispense(140); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		denomination int
+		count        int
+		load         bool
+		expected     error
+	}{
+		{"load unknown", 100, 1, true, ErrUnknownCassette},
+		{"load zero", 50, 0, true, ErrInvalidCount},
+		{"remove too many", 20, 3, false, ErrInvalidCount},
+		{"remove negative", 20, -1, false, ErrInvalidCount},
+	}
+	for _, test := range tests {
+		var err error
+		if test.load {
+			err = d.Load(test.denomination, test.count)
+		} else {
+			err = d.Remove(test.denomination, test.count)
+		}
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: Expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+
+	// 140 is paid as 2 x $50 and 2 x $20
+	expected := []CassetteReport{
+		{Denomination: 50, Count: 10, Dispensed: 2, Loaded: 10},
+		{Denomination: 20, Count: 0, Dispensed: 2, Removed: 3},
+	}
+	if !reflect.DeepEqual(d.Report(), expected) {
+		t.Errorf("Expected %+v, got %+v", expected, d.Report())
+	}
+
+	d.ResetCounters()
+	expected = []CassetteReport{{Denomination: 50, Count: 10}, {Denomination: 20, Count: 0}}
+	if !reflect.DeepEqual(d.Report(), expected) {
+		t.Errorf("Expected counters to be reset, got %+v", d.Report())
+	}
+}
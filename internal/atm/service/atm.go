@@ -2,29 +2,139 @@ package atm_service
 
 import (
 	account_repository "atm-simulation-console/internal/account/repository"
+	atm_dispenser "atm-simulation-console/internal/atm/dispenser"
+	"atm-simulation-console/internal/config"
+	interbank_switch "atm-simulation-console/internal/interbank/switch"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"atm-simulation-console/internal/util/formatter"
+	"atm-simulation-console/internal/util/hasher"
 	"bufio"
 	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+type BalanceInquiry struct {
+	Name             string
+	AvailableBalance int
+	LedgerBalance    int
+}
+
 type ATMService struct {
-	repo *account_repository.AccountRepository
+	repo      account_repository.AccountStore
+	ledger    transaction_repository.LedgerStore
+	cfg       config.Config
+	dispenser *atm_dispenser.Dispenser
+	interbank interbank_switch.Switch
+	now       func() time.Time
+
+	mu               sync.Mutex
+	balancedAt       time.Time
+	pending          map[string]pendingTransfer
+	operatorFailures int
 }
 
-func NewATMService(repo *account_repository.AccountRepository) *ATMService {
-	return &ATMService{
-		repo: repo,
+// NewATMService loads the configured cassettes into the cash dispenser. With
+// no cassettes configured the machine never runs out of cash.
+func NewATMService(repo account_repository.AccountStore, ledger transaction_repository.LedgerStore, cfg config.Config) *ATMService {
+	s := &ATMService{
+		repo:    repo,
+		ledger:  ledger,
+		cfg:     cfg,
+		now:     time.Now,
+		pending: make(map[string]pendingTransfer),
+	}
+	s.balancedAt = s.now()
+	if len(cfg.Cassettes) > 0 {
+		cassettes := make([]atm_dispenser.Cassette, 0, len(cfg.Cassettes))
+		for _, c := range cfg.Cassettes {
+			cassettes = append(cassettes, atm_dispenser.Cassette{Denomination: c.Denomination, Count: c.Count})
+		}
+		s.dispenser = atm_dispenser.NewDispenser(cassettes)
 	}
+	s.restorePending()
+	return s
+}
+
+// SetClock replaces the clock used for timestamps, daily limits and fees.
+// The totals report period starts over at the new clock's time.
+func (s *ATMService) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+	s.balancedAt = now()
 }
 
+// Now is the time on the service's clock.
+func (s *ATMService) Now() time.Time {
+	return s.now()
+}
+
+func (s *ATMService) Config() config.Config {
+	return s.cfg
+}
+
+// AddAccount stores the account with its PIN hashed. PINs that are already
+// hashed are kept as they are. The operator card number cannot be used.
 func (s *ATMService) AddAccount(account account_repository.Account) bool {
+	if s.IsOperatorCard(account.AccountNumber) {
+		return false
+	}
+	if !hasher.IsHashed(account.Pin) {
+		hash, err := hasher.HashPIN(account.Pin)
+		if err != nil {
+			return false
+		}
+		account.Pin = hash
+	}
+	if account.Status == "" {
+		account.Status = account_repository.StatusActive
+	}
 	return s.repo.AddAccount(account)
 }
 
+// MigratePlaintextPINs hashes every PIN still stored as plaintext and returns
+// how many accounts were migrated.
+func (s *ATMService) MigratePlaintextPINs() (int, error) {
+	migrated := 0
+	for _, acc := range s.repo.ListAccounts() {
+		// accounts without a PIN, like the fee income account, stay locked
+		if acc.Pin == "" || hasher.IsHashed(acc.Pin) {
+			continue
+		}
+		if err := s.upgradePIN(acc.AccountNumber, acc.Pin); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+func (s *ATMService) AccountExists(accNumber string) bool {
+	return s.repo.FindAccount(accNumber) != nil
+}
+
+// AddSampleAccounts opens the demo accounts unless they already exist, so
+// balances restored from persistent storage are kept.
+func (s *ATMService) AddSampleAccounts() {
+	for _, account := range []account_repository.Account{
+		{AccountNumber: "112233", Name: "John Doe", Pin: "123123", Balance: 100},
+		{AccountNumber: "112244", Name: "Jane Doe", Pin: "123123", Balance: 30},
+	} {
+		if s.AccountExists(account.AccountNumber) {
+			continue
+		}
+		s.AddAccount(account)
+	}
+}
+
 func (s *ATMService) ValidateAccount(accNumber string) (*account_repository.Account, error) {
-	if err := validateLength(accNumber, 6, "account number"); err != nil {
+	if err := validateLength(accNumber, s.cfg.AccountNumberLength, "account number"); err != nil {
 		return nil, err
 	}
 	if err := validateDigitsOnly(accNumber, "account number"); err != nil {
@@ -33,106 +143,542 @@ func (s *ATMService) ValidateAccount(accNumber string) (*account_repository.Acco
 
 	acc := s.repo.FindAccount(accNumber)
 	if acc == nil {
-		return nil, errors.New("invalid account number")
+		return nil, &InvalidAccountError{AccountNumber: accNumber}
+	}
+	if acc.Status == account_repository.StatusBlocked && acc.FailedPINAttempts >= s.cfg.MaxPINAttempts {
+		return nil, &AccountStatusError{AccountNumber: accNumber, Status: acc.Status, Reason: pinBlockedReason}
+	}
+	if err := checkDebitStatus(acc); err != nil {
+		return nil, err
 	}
 
 	return acc, nil
 }
 
-func (s *ATMService) ValidatePIN(account *account_repository.Account, pin string) (*account_repository.Account, error) {
-	if err := validateLength(pin, 6, "PIN"); err != nil {
+func (s *ATMService) ValidateTransferDestination(srcNumber, destNumber string) (*account_repository.Account, error) {
+	if err := validateLength(destNumber, s.cfg.AccountNumberLength, "account number"); err != nil {
 		return nil, err
 	}
-	if err := validateDigitsOnly(pin, "PIN"); err != nil {
+	if err := validateDigitsOnly(destNumber, "account number"); err != nil {
 		return nil, err
 	}
 
-	if account.Pin != pin {
-		return nil, errors.New("invalid account number/PIN")
+	acc := s.repo.FindAccount(destNumber)
+	if acc == nil {
+		return nil, &InvalidAccountError{AccountNumber: destNumber, Destination: true}
+	}
+	if srcNumber == destNumber {
+		return nil, ErrSameAccount
+	}
+	if err := checkCreditStatus(acc, true); err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
+// ValidatePIN checks pin against the account's stored PIN. Every rejected
+// entry, malformed or wrong, counts towards blocking the account.
+func (s *ATMService) ValidatePIN(account *account_repository.Account, pin string) (*account_repository.Account, error) {
+	if current := s.repo.FindAccount(account.AccountNumber); current != nil {
+		account = current
+	}
+	if account.Status == account_repository.StatusBlocked {
+		return nil, &AccountStatusError{AccountNumber: account.AccountNumber, Status: account.Status}
+	}
+
+	err := validateLength(pin, s.cfg.PINLength, "PIN")
+	if err == nil {
+		err = validateDigitsOnly(pin, "PIN")
+	}
+	if err == nil && !hasher.VerifyPIN(pin, account.Pin) {
+		err = ErrInvalidPIN
+	}
+	if err != nil {
+		if s.registerFailedPIN(account.AccountNumber) {
+			return nil, &AccountStatusError{
+				AccountNumber: account.AccountNumber,
+				Status:        account_repository.StatusBlocked,
+				Reason:        pinBlockedReason,
+			}
+		}
+		return nil, err
+	}
+	if account.FailedPINAttempts > 0 {
+		s.repo.UpdateAccount(account.AccountNumber, func(acc *account_repository.Account) error {
+			acc.FailedPINAttempts = 0
+			return nil
+		})
+	}
+	if !hasher.IsHashed(account.Pin) {
+		// best effort: a failed upgrade leaves the legacy PIN usable
+		s.upgradePIN(account.AccountNumber, pin)
 	}
 
 	return account, nil
 }
 
+// SetAccountStatus moves an account through its lifecycle. Closed accounts
+// cannot be reopened.
+func (s *ATMService) SetAccountStatus(accNumber string, status account_repository.AccountStatus) error {
+	if !status.IsValid() {
+		return ErrInvalidStatus
+	}
+	return s.updateAccount(accNumber, func(acc *account_repository.Account) error {
+		if acc.CurrentStatus() == account_repository.StatusClosed && status != account_repository.StatusClosed {
+			return &AccountStatusError{AccountNumber: accNumber, Status: account_repository.StatusClosed}
+		}
+		acc.Status = status
+		if status == account_repository.StatusActive {
+			acc.FailedPINAttempts = 0
+		}
+		return nil
+	})
+}
+
+func (s *ATMService) IsAccountBlocked(accNumber string) bool {
+	acc := s.repo.FindAccount(accNumber)
+	return acc != nil && acc.Status == account_repository.StatusBlocked
+}
+
+// UnblockAccount is the operator path for accounts blocked by wrong PINs.
+func (s *ATMService) UnblockAccount(accNumber string) error {
+	return s.updateAccount(accNumber, func(acc *account_repository.Account) error {
+		if acc.Status != account_repository.StatusBlocked {
+			return ErrAccountNotBlocked
+		}
+		acc.Status = account_repository.StatusActive
+		acc.FailedPINAttempts = 0
+		return nil
+	})
+}
+
+// updateAccount is UpdateAccount with the repository's not-found error
+// translated into the service's own.
+func (s *ATMService) updateAccount(accNumber string, update func(acc *account_repository.Account) error) error {
+	err := s.repo.UpdateAccount(accNumber, update)
+	if errors.Is(err, account_repository.ErrAccountNotFound) {
+		return &InvalidAccountError{AccountNumber: accNumber}
+	}
+	return err
+}
+
+// updateAccounts is UpdateAccounts with the repository's not-found error
+// translated into the service's own. Every account after the first is a
+// destination.
+func (s *ATMService) updateAccounts(numbers []string, update func(accounts []*account_repository.Account) error) error {
+	err := s.repo.UpdateAccounts(numbers, update)
+	if errors.Is(err, account_repository.ErrAccountNotFound) {
+		for i, number := range numbers {
+			if !s.AccountExists(number) {
+				return &InvalidAccountError{AccountNumber: number, Destination: i > 0}
+			}
+		}
+	}
+	return err
+}
+
+// registerFailedPIN counts a wrong PIN and reports whether it blocked the account.
+func (s *ATMService) registerFailedPIN(accNumber string) bool {
+	blocked := false
+	s.repo.UpdateAccount(accNumber, func(acc *account_repository.Account) error {
+		acc.FailedPINAttempts++
+		if acc.FailedPINAttempts >= s.cfg.MaxPINAttempts {
+			acc.Status = account_repository.StatusBlocked
+			blocked = true
+		}
+		return nil
+	})
+	return blocked
+}
+
+func (s *ATMService) ChangePIN(accNumber, currentPin, newPin, confirmPin string) error {
+	acc := s.repo.FindAccount(accNumber)
+	if acc == nil {
+		return &InvalidAccountError{AccountNumber: accNumber}
+	}
+	if _, err := s.ValidatePIN(acc, currentPin); err != nil {
+		if s.IsAccountBlocked(accNumber) {
+			return err
+		}
+		return ErrInvalidCurrentPIN
+	}
+
+	if err := validateNewPIN(accNumber, currentPin, newPin, s.cfg.PINLength); err != nil {
+		return err
+	}
+	if newPin != confirmPin {
+		return ErrPINMismatch
+	}
+
+	hash, err := hasher.HashPIN(newPin)
+	if err != nil {
+		return err
+	}
+	return s.updateAccount(accNumber, func(account *account_repository.Account) error {
+		account.Pin = hash
+		return nil
+	})
+}
+
+// upgradePIN replaces a plaintext PIN with its hash, unless the stored PIN
+// changed in the meantime.
+func (s *ATMService) upgradePIN(accNumber, plaintext string) error {
+	hash, err := hasher.HashPIN(plaintext)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateAccount(accNumber, func(account *account_repository.Account) error {
+		if account.Pin == plaintext {
+			account.Pin = hash
+		}
+		return nil
+	})
+}
+
 func (s *ATMService) GetBalance(accNumber string) int {
 	return s.repo.GetBalance(accNumber)
 }
 
+// BalanceInquiry reports the ledger balance and the part of it available for
+// withdrawal, which excludes funds held for pending interbank transfers.
+func (s *ATMService) BalanceInquiry(accNumber string) (BalanceInquiry, error) {
+	s.SettleInterbankTransfers()
+
+	acc := s.repo.FindAccount(accNumber)
+	if acc == nil {
+		return BalanceInquiry{}, &InvalidAccountError{AccountNumber: accNumber}
+	}
+
+	return BalanceInquiry{
+		Name:             acc.Name,
+		AvailableBalance: acc.Available(),
+		LedgerBalance:    acc.Balance,
+	}, nil
+}
+
+// GetAvailableBalance returns the balance less any held funds.
+func (s *ATMService) GetAvailableBalance(accNumber string) int {
+	acc := s.repo.FindAccount(accNumber)
+	if acc == nil {
+		return 0
+	}
+	return acc.Available()
+}
+
 func (s *ATMService) CheckBalance(accNumber string, amount int) error {
-	currentBalance := s.GetBalance(accNumber)
-	if currentBalance < amount {
-		return errors.New("insufficient balance " + "$" + strconv.Itoa(amount))
+	available := s.GetAvailableBalance(accNumber)
+	if available < amount {
+		return &InsufficientFundsError{Requested: amount, Available: available}
 	}
 
 	return nil
 }
 
 func (s *ATMService) ValidateOtherWithdraw(accNumber string, amount int) error {
-	if amount%10 != 0 {
-		return errors.New("invalid amount: must be a multiple of 10")
+	if err := s.checkWithdrawAmount(amount); err != nil {
+		return err
 	}
 
-	if amount > 1000 {
-		return errors.New("maximum amount to withdraw is $1000")
+	if err := s.checkDailyLimit(accNumber, ChannelWithdraw, amount); err != nil {
+		return err
 	}
 
-	currentBalance := s.GetBalance(accNumber)
-	if currentBalance < amount {
-		return errors.New("insufficient balance " + "$" + strconv.Itoa(amount))
+	if err := s.CheckBalance(accNumber, amount); err != nil {
+		return err
 	}
+	return s.CheckCash(amount)
+}
 
-	return s.CheckBalance(accNumber, amount)
+// CheckCash reports whether the cassettes can pay amount out exactly, so the
+// customer hears before confirming rather than after.
+func (s *ATMService) CheckCash(amount int) error {
+	if s.dispenser == nil {
+		return nil
+	}
+	_, err := s.dispenser.Plan(amount)
+	return err
 }
 
 func (s *ATMService) ValidateTransferAmount(accNumber string, amount int) error {
-	if amount < 0 {
-		return errors.New("minimum amount to transfer is $1")
+	if err := s.checkTransferAmount(amount); err != nil {
+		return err
+	}
+
+	if err := s.checkDailyLimit(accNumber, ChannelTransfer, amount); err != nil {
+		return err
 	}
 
-	if amount > 1000 {
-		return errors.New("maximum amount to transfer is $1000")
+	return s.CheckBalance(accNumber, amount)
+}
+
+func (s *ATMService) ValidateDeposit(amount int) error {
+	if amount <= 0 {
+		return &InvalidAmountError{Amount: amount, Reason: "minimum amount to deposit is " + formatter.CurrencyFormatter(s.cfg.NoteMultiple)}
 	}
 
-	currentBalance := s.GetBalance(accNumber)
-	if currentBalance < amount {
-		return errors.New("insufficient balance " + "$" + strconv.Itoa(amount))
+	if err := s.checkNoteMultiple(amount); err != nil {
+		return err
 	}
 
-	return s.CheckBalance(accNumber, amount)
+	if amount > s.cfg.Deposit.Max {
+		return &LimitExceededError{Operation: "deposit", Limit: s.cfg.Deposit.Max, Requested: amount}
+	}
+
+	return nil
+}
+
+// checkWithdrawAmount applies the per-transaction withdrawal rules.
+func (s *ATMService) checkWithdrawAmount(amount int) error {
+	if amount <= 0 {
+		return &InvalidAmountError{Amount: amount, Reason: "minimum amount to withdraw is " + formatter.CurrencyFormatter(s.cfg.NoteMultiple)}
+	}
+	if err := s.checkNoteMultiple(amount); err != nil {
+		return err
+	}
+	if amount > s.cfg.Withdraw.Max {
+		return &LimitExceededError{Operation: "withdraw", Limit: s.cfg.Withdraw.Max, Requested: amount}
+	}
+	return nil
+}
+
+// checkTransferAmount applies the per-transaction transfer rules.
+func (s *ATMService) checkTransferAmount(amount int) error {
+	if amount < s.cfg.Transfer.Min {
+		return &InvalidAmountError{Amount: amount, Reason: "minimum amount to transfer is " + formatter.CurrencyFormatter(s.cfg.Transfer.Min)}
+	}
+	if amount > s.cfg.Transfer.Max {
+		return &LimitExceededError{Operation: "transfer", Limit: s.cfg.Transfer.Max, Requested: amount}
+	}
+	return nil
 }
 
-func (s *ATMService) Withdraw(accNumber string, amount int) bool {
-	return s.repo.Withdraw(accNumber, amount)
+func (s *ATMService) checkNoteMultiple(amount int) error {
+	if amount%s.cfg.NoteMultiple != 0 {
+		return &InvalidAmountError{Amount: amount, Reason: "invalid amount: must be a multiple of " + strconv.Itoa(s.cfg.NoteMultiple)}
+	}
+	return nil
 }
 
-func (s *ATMService) Deposit(accNumber string, amount int) bool {
-	return s.repo.Deposit(accNumber, amount)
+// Withdraw debits the account and pays the amount out of the dispenser. It
+// returns the notes handed over, or nil when the machine has unlimited cash.
+// Any fee is charged on top of the amount.
+func (s *ATMService) Withdraw(accNumber string, amount int) ([]atm_dispenser.Note, error) {
+	notes, p, err := s.withdraw(accNumber, amount)
+
+	s.record(transaction_repository.Transaction{
+		AccountNumber: accNumber,
+		Type:          transaction_repository.TypeWithdraw,
+		Amount:        amount,
+		Balance:       p.balance,
+	}, err)
+	if err == nil {
+		s.recordFee(accNumber, "", p)
+	}
+	return notes, err
 }
 
-func (s *ATMService) Transfer(srcNumber, destNumber string, amount int) error {
+func (s *ATMService) withdraw(accNumber string, amount int) ([]atm_dispenser.Note, posting, error) {
+	if err := s.checkWithdrawAmount(amount); err != nil {
+		return nil, posting{}, err
+	}
+	if err := s.checkAccountStatus(accNumber, checkDebitStatus); err != nil {
+		return nil, posting{}, err
+	}
+	if err := s.checkDailyLimit(accNumber, ChannelWithdraw, amount); err != nil {
+		return nil, posting{}, err
+	}
+	if err := s.CheckBalance(accNumber, amount+s.Fee(accNumber, ChannelWithdraw, amount)); err != nil {
+		return nil, posting{}, err
+	}
 
-	destNum := s.repo.FindAccount(destNumber)
-	if destNum == nil {
-		return errors.New("invalid destination account")
+	var notes []atm_dispenser.Note
+	if s.dispenser != nil {
+		var err error
+		if notes, err = s.dispenser.Dispense(amount); err != nil {
+			return nil, posting{}, err
+		}
 	}
+	var p posting
+	var err error
+	p.incomeBalance, err = s.withFee([]string{accNumber}, func(accounts []*account_repository.Account) (int, error) {
+		acc := accounts[0]
+		var err error
+		if p.fee, err = s.authorize(acc, ChannelWithdraw, amount); err != nil {
+			return 0, err
+		}
+		acc.Balance -= amount
+		p.balance = acc.Balance
+		return p.fee, nil
+	})
+	if err != nil {
+		if s.dispenser != nil {
+			s.dispenser.Return(notes)
+		}
+		return nil, posting{}, err
+	}
+	return notes, p, nil
+}
 
-	if s.repo.Withdraw(srcNumber, amount) {
-		s.repo.Deposit(destNumber, amount)
-		return nil
+// OutOfCash reports whether the dispenser has no notes left.
+func (s *ATMService) OutOfCash() bool {
+	return s.dispenser != nil && s.dispenser.OutOfCash()
+}
+
+// Deposit credits amount, checking the account's status in the same update
+// so an account closed meanwhile never takes the money.
+func (s *ATMService) Deposit(accNumber string, amount int, ref string) error {
+	var balance int
+	err := s.ValidateDeposit(amount)
+	if err == nil {
+		err = s.updateAccount(accNumber, func(acc *account_repository.Account) error {
+			if err := checkCreditStatus(acc, false); err != nil {
+				return err
+			}
+			acc.Balance += amount
+			balance = acc.Balance
+			return nil
+		})
+	}
+
+	s.record(transaction_repository.Transaction{
+		AccountNumber: accNumber,
+		Type:          transaction_repository.TypeDeposit,
+		Amount:        amount,
+		Balance:       balance,
+		Reference:     ref,
+	}, err)
+	return err
+}
+
+// Transfer moves amount between two accounts of this bank. Any fee is
+// charged to the source on top of the amount.
+func (s *ATMService) Transfer(srcNumber, destNumber string, amount int, ref string) error {
+	p, err := s.transfer(srcNumber, destNumber, amount)
+
+	s.record(transaction_repository.Transaction{
+		AccountNumber: srcNumber,
+		Type:          transaction_repository.TypeTransferOut,
+		Amount:        amount,
+		Balance:       p.balance,
+		Counterparty:  destNumber,
+		Reference:     ref,
+	}, err)
+	if err == nil {
+		s.record(transaction_repository.Transaction{
+			AccountNumber: destNumber,
+			Type:          transaction_repository.TypeTransferIn,
+			Amount:        amount,
+			Balance:       p.destBalance,
+			Counterparty:  srcNumber,
+			Reference:     ref,
+		}, nil)
+		s.recordFee(srcNumber, ref, p)
+	}
+	return err
+}
+
+func (s *ATMService) transfer(srcNumber, destNumber string, amount int) (posting, error) {
+	if srcNumber == destNumber {
+		return posting{}, ErrSameAccount
+	}
+	if err := s.checkTransferAmount(amount); err != nil {
+		return posting{}, err
 	}
-	return errors.New("insufficient balance " + "$" + strconv.Itoa(amount))
+
+	var p posting
+	var err error
+	p.incomeBalance, err = s.withFee([]string{srcNumber, destNumber}, func(accounts []*account_repository.Account) (int, error) {
+		src, dest := accounts[0], accounts[1]
+		if err := checkDebitStatus(src); err != nil {
+			return 0, err
+		}
+		if err := checkCreditStatus(dest, true); err != nil {
+			return 0, err
+		}
+		var err error
+		if p.fee, err = s.authorize(src, ChannelTransfer, amount); err != nil {
+			return 0, err
+		}
+		src.Balance -= amount
+		dest.Balance += amount
+		p.balance, p.destBalance = src.Balance, dest.Balance
+		return p.fee, nil
+	})
+	if err != nil {
+		return posting{}, err
+	}
+	return p, nil
+}
+
+// authorize checks a debit of amount through channel against the account's
+// status, daily limit and available balance, counts it towards the limit and
+// returns the fee it comes with. Callers run it inside a repository update,
+// so the checks see the account exactly as it is changed.
+func (s *ATMService) authorize(acc *account_repository.Account, channel Channel, amount int) (int, error) {
+	if err := checkDebitStatus(acc); err != nil {
+		return 0, err
+	}
+	if err := s.checkUsage(acc, channel, amount); err != nil {
+		return 0, err
+	}
+	fee := s.fee(acc, channel, amount)
+	if acc.Available() < amount+fee {
+		return 0, &InsufficientFundsError{Requested: amount + fee, Available: acc.Available()}
+	}
+	s.use(acc, channel, amount)
+	return fee, nil
+}
+
+// GetTransactions returns the account's ledger entries with
+// from <= Timestamp < to. A zero from or to leaves that side open.
+func (s *ATMService) GetTransactions(accNumber string, from, to time.Time) []transaction_repository.Transaction {
+	return s.ledger.FindByAccount(accNumber, from, to)
+}
+
+// MiniStatement returns the account's last n completed transactions, oldest first.
+func (s *ATMService) MiniStatement(accNumber string, n int) []transaction_repository.Transaction {
+	s.SettleInterbankTransfers()
+
+	var completed []transaction_repository.Transaction
+	for _, trx := range s.ledger.FindByAccount(accNumber, time.Time{}, time.Time{}) {
+		if trx.Status == transaction_repository.StatusSuccess {
+			completed = append(completed, trx)
+		}
+	}
+	if len(completed) > n {
+		completed = completed[len(completed)-n:]
+	}
+	return completed
+}
+
+// record stamps trx and appends it to the ledger. Without an error it is
+// recorded as SUCCESS unless trx already carries a status such as PENDING,
+// with the Balance the operation returned. A failed operation changed
+// nothing, so it is recorded with the current balance.
+func (s *ATMService) record(trx transaction_repository.Transaction, err error) transaction_repository.Transaction {
+	trx.Timestamp = s.now()
+	if trx.Status == "" {
+		trx.Status = transaction_repository.StatusSuccess
+	}
+	if err != nil {
+		trx.Status = transaction_repository.StatusFailed
+		trx.Reason = err.Error()
+		trx.Balance = s.repo.GetBalance(trx.AccountNumber)
+	}
+	return s.ledger.Record(trx)
 }
 
 func (s *ATMService) GetInputNumber(reader *bufio.Reader) (int, error) {
 	amountStr, err := reader.ReadString('\n')
 	if err != nil {
-		return 0, errors.New("invalid input")
+		return 0, ErrInvalidInput
 	}
 	amountStr = strings.TrimSpace(amountStr)
 
 	amount, err := strconv.Atoi(amountStr)
 	if err != nil {
-		return 0, errors.New("invalid input: please enter a valid number")
+		return 0, fmt.Errorf("%w: please enter a valid number", ErrInvalidInput)
 	}
 	return amount, nil
 }
@@ -144,9 +690,69 @@ func (s *ATMService) GetInputString(reader *bufio.Reader) string {
 	return input
 }
 
+func (s *ATMService) checkAccountStatus(accNumber string, check func(acc *account_repository.Account) error) error {
+	acc := s.repo.FindAccount(accNumber)
+	if acc == nil {
+		return &InvalidAccountError{AccountNumber: accNumber}
+	}
+	return check(acc)
+}
+
+// checkDebitStatus only lets active accounts log in or move money out.
+func checkDebitStatus(acc *account_repository.Account) error {
+	if status := acc.CurrentStatus(); status != account_repository.StatusActive {
+		return &AccountStatusError{AccountNumber: acc.AccountNumber, Status: status}
+	}
+	return nil
+}
+
+// checkCreditStatus lets every account except closed ones receive money, so
+// salaries keep arriving on blocked and dormant accounts.
+func checkCreditStatus(acc *account_repository.Account, destination bool) error {
+	if acc.CurrentStatus() == account_repository.StatusClosed {
+		return &AccountStatusError{AccountNumber: acc.AccountNumber, Status: account_repository.StatusClosed, Destination: destination}
+	}
+	return nil
+}
+
+func validateNewPIN(accNumber, currentPin, newPin string, length int) error {
+	if err := validateLength(newPin, length, "new PIN"); err != nil {
+		return err
+	}
+	if err := validateDigitsOnly(newPin, "new PIN"); err != nil {
+		return err
+	}
+
+	if newPin == currentPin {
+		return &WeakPINError{Reason: "be different from the current PIN"}
+	}
+	if newPin == accNumber {
+		return &WeakPINError{Reason: "not be the same as the account number"}
+	}
+	if strings.Count(newPin, newPin[:1]) == len(newPin) {
+		return &WeakPINError{Reason: "not repeat the same digit"}
+	}
+	if isSequential(newPin) {
+		return &WeakPINError{Reason: "not be a sequence of digits"}
+	}
+	return nil
+}
+
+// isSequential reports whether every digit is one above (or every digit one
+// below) the previous one, e.g. 123456 or 987654.
+func isSequential(pin string) bool {
+	ascending, descending := true, true
+	for i := 1; i < len(pin); i++ {
+		diff := int(pin[i]) - int(pin[i-1])
+		ascending = ascending && diff == 1
+		descending = descending && diff == -1
+	}
+	return ascending || descending
+}
+
 func validateLength(input string, length int, fieldName string) error {
 	if len(input) != length {
-		return errors.New(fieldName + " should have " + strconv.Itoa(length) + " digits length")
+		return &ValidationError{Field: fieldName, Reason: "should have " + strconv.Itoa(length) + " digits length"}
 	}
 	return nil
 }
@@ -154,7 +760,7 @@ func validateLength(input string, length int, fieldName string) error {
 func validateDigitsOnly(input string, fieldName string) error {
 
 	if matched, _ := regexp.MatchString(`^\d{`+strconv.Itoa(len(input))+`}$`, input); !matched {
-		return errors.New(fieldName + " should only contain numbers")
+		return &ValidationError{Field: fieldName, Reason: "should only contain numbers"}
 	}
 	return nil
 }
@@ -2,14 +2,22 @@ package atm_service
 
 import (
 	account_repository "atm-simulation-console/internal/account/repository"
+	atm_dispenser "atm-simulation-console/internal/atm/dispenser"
+	"atm-simulation-console/internal/config"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"atm-simulation-console/internal/util/hasher"
 	"bufio"
+	"errors"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestAddAccount(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 
 	// Add test account
 	testAccount := account_repository.Account{
@@ -22,11 +30,20 @@ func TestAddAccount(t *testing.T) {
 	if !atmSvc.AddAccount(testAccount) {
 		t.Error("Expected true for successful addition of account, got false")
 	}
+
+	// Test PIN is stored hashed
+	stored := repo.FindAccount("123456")
+	if stored.Pin == testAccount.Pin || !hasher.IsHashed(stored.Pin) {
+		t.Errorf("Expected hashed PIN, got %q", stored.Pin)
+	}
+	if _, err := atmSvc.ValidatePIN(stored, "111111"); err == nil {
+		t.Error("Expected error for wrong PIN against hash, got nil")
+	}
 }
 
 func TestValidateAccount(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 
 	// Add test account
 	testAccount := account_repository.Account{
@@ -63,7 +80,7 @@ func TestValidateAccount(t *testing.T) {
 
 func TestValidatePIN(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 
 	// Add test account
 	testAccount := account_repository.Account{
@@ -79,6 +96,15 @@ func TestValidatePIN(t *testing.T) {
 		t.Error("Expected valid account, got nil")
 	}
 
+	// Test legacy plaintext PIN is upgraded to a hash on successful login
+	upgraded := repo.FindAccount("123456")
+	if !hasher.IsHashed(upgraded.Pin) {
+		t.Errorf("Expected hashed PIN after login, got %q", upgraded.Pin)
+	}
+	if acc, _ := atmSvc.ValidatePIN(upgraded, "111111"); acc == nil {
+		t.Error("Expected valid account with hashed PIN, got nil")
+	}
+
 	// Test invalid account : less than 6 digits
 	acc, _ := atmSvc.ValidatePIN(&testAccount, "123")
 	if acc != nil {
@@ -100,7 +126,7 @@ func TestValidatePIN(t *testing.T) {
 
 func TestTransfer(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 
 	// Add test accounts
 	srcAccount := account_repository.Account{
@@ -150,7 +176,7 @@ func TestTransfer(t *testing.T) {
 	}
 
 	// Test successful transfer
-	err = atmSvc.Transfer("123456", "987654", 500)
+	err = atmSvc.Transfer("123456", "987654", 500, "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -162,21 +188,63 @@ func TestTransfer(t *testing.T) {
 	}
 
 	// Test failed transfer due to invalid destination account
-	err = atmSvc.Transfer("123456", "999999", 500)
+	err = atmSvc.Transfer("123456", "999999", 500, "")
 	if err == nil {
 		t.Error("Expected false for failed transfer (invalid destination account), got true")
 	}
 
 	// Test failed transfer due to insufficient balance
-	err = atmSvc.Transfer("123456", "987654", 1500)
+	err = atmSvc.Transfer("123456", "987654", 1500, "")
 	if err == nil {
 		t.Errorf("Expected 'insufficient balance' error message, got %s", err)
 	}
+
+	// Test failed transfer to the same account
+	repo.Deposit("123456", 100)
+	err = atmSvc.Transfer("123456", "123456", 50, "")
+	if err == nil {
+		t.Error("Expected error for transfer to the same account, got nil")
+	}
+
+	// Test failed transfer of non-positive amounts
+	for _, amount := range []int{0, -50} {
+		err = atmSvc.Transfer("123456", "987654", amount, "")
+		if err == nil {
+			t.Errorf("Expected error for amount %d, got nil", amount)
+		}
+	}
+	if repo.GetBalance("123456") != 100 || repo.GetBalance("987654") != 2500 {
+		t.Errorf("Expected balances untouched, got %d/%d", repo.GetBalance("123456"), repo.GetBalance("987654"))
+	}
+
+	// zero is below the minimum transfer amount
+	err = atmSvc.ValidateTransferAmount("123456", 0)
+	if err == nil {
+		t.Error("Expected error for zero amount, got nil")
+	}
+}
+
+func TestValidateTransferDestination(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 500})
+	repo.AddAccount(account_repository.Account{AccountNumber: "987654", Balance: 500})
+
+	if acc, err := atmSvc.ValidateTransferDestination("123456", "987654"); acc == nil {
+		t.Errorf("Expected destination account, got %v", err)
+	}
+	if acc, _ := atmSvc.ValidateTransferDestination("123456", "123456"); acc != nil {
+		t.Error("Expected nil for same account, got account")
+	}
+	if acc, _ := atmSvc.ValidateTransferDestination("123456", "111111"); acc != nil {
+		t.Error("Expected nil for unknown account, got account")
+	}
 }
 
 func TestValidateOtherWithdraw(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 
 	// Add test accounts
 	srcAccount := account_repository.Account{
@@ -209,7 +277,7 @@ func TestValidateOtherWithdraw(t *testing.T) {
 
 func TestGetInputNumber(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 	tests := []struct {
 		input     string
 		expected  int
@@ -232,7 +300,7 @@ func TestGetInputNumber(t *testing.T) {
 
 func TestGetInputString(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 	tests := []struct {
 		input     string
 		expected  string
@@ -254,7 +322,7 @@ func TestGetInputString(t *testing.T) {
 
 func TestGetBalance(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 
 	// Add test account
 	testAccount := account_repository.Account{
@@ -273,7 +341,7 @@ func TestGetBalance(t *testing.T) {
 
 func TestWithdraw(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 
 	// Add test account
 	testAccount := account_repository.Account{
@@ -284,22 +352,84 @@ func TestWithdraw(t *testing.T) {
 	repo.AddAccount(testAccount)
 
 	// Test successful withdrawal
-	if !atmSvc.Withdraw("123456", 500) {
-		t.Error("Expected true for successful withdrawal, got false")
+	if _, err := atmSvc.Withdraw("123456", 500); err != nil {
+		t.Errorf("Expected no error for successful withdrawal, got %v", err)
 	}
 	if repo.GetBalance("123456") != 500 {
 		t.Errorf("Expected balance of 500 after withdrawal, got %d", repo.GetBalance("123456"))
 	}
 
 	// Test failed withdrawal due to insufficient balance
-	if atmSvc.Withdraw("123456", 600) {
-		t.Error("Expected false for failed withdrawal due to insufficient balance, got true")
+	if _, err := atmSvc.Withdraw("123456", 600); err == nil {
+		t.Error("Expected error for failed withdrawal due to insufficient balance, got nil")
+	}
+}
+
+func TestWithdrawDispensesNotes(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	ledger := transaction_repository.NewTransactionRepository()
+	cfg := config.Default()
+	cfg.Cassettes = []config.CassetteConfig{{Denomination: 50, Count: 2}, {Denomination: 20, Count: 3}}
+	atmSvc := NewATMService(repo, ledger, cfg)
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Pin: "1234", Balance: 1000})
+
+	notes, err := atmSvc.Withdraw("123456", 110)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []atm_dispenser.Note{{Denomination: 50, Count: 1}, {Denomination: 20, Count: 3}}
+	if !reflect.DeepEqual(notes, expected) {
+		t.Errorf("Expected %v, got %v", expected, notes)
+	}
+
+	// $50 is left and cannot make $30
+	if err := atmSvc.ValidateOtherWithdraw("123456", 30); !errors.Is(err, ErrCannotDispense) {
+		t.Errorf("Expected validation to report ErrCannotDispense, got %v", err)
+	}
+	if err := atmSvc.CheckCash(50); err != nil {
+		t.Errorf("Expected $50 to be payable, got %v", err)
+	}
+	if _, err := atmSvc.Withdraw("123456", 30); !errors.Is(err, ErrCannotDispense) {
+		t.Errorf("Expected ErrCannotDispense, got %v", err)
+	}
+	if repo.GetBalance("123456") != 890 {
+		t.Errorf("Expected failed dispense to leave balance at 890, got %d", repo.GetBalance("123456"))
+	}
+	trxs := ledger.FindByAccount("123456", time.Time{}, time.Time{})
+	if last := trxs[len(trxs)-1]; last.Status != transaction_repository.StatusFailed {
+		t.Errorf("Expected failed dispense to be recorded as FAILED, got %s", last.Status)
+	}
+
+	if _, err := atmSvc.Withdraw("123456", 50); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !atmSvc.OutOfCash() {
+		t.Error("Expected ATM to be out of cash")
+	}
+	if _, err := atmSvc.Withdraw("123456", 10); !errors.Is(err, ErrOutOfCash) {
+		t.Errorf("Expected ErrOutOfCash, got %v", err)
+	}
+}
+
+func TestWithdrawUnlimitedCash(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.Cassettes = nil
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Pin: "1234", Balance: 10000})
+
+	notes, err := atmSvc.Withdraw("123456", 1000)
+	if err != nil || notes != nil {
+		t.Errorf("Expected no notes and no error, got %v, %v", notes, err)
+	}
+	if atmSvc.OutOfCash() {
+		t.Error("Expected ATM without cassettes never to run out of cash")
 	}
 }
 
 func TestDeposit(t *testing.T) {
 	repo := account_repository.NewAccountRepository()
-	atmSvc := NewATMService(repo)
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
 
 	// Add test account
 	testAccount := account_repository.Account{
@@ -310,10 +440,483 @@ func TestDeposit(t *testing.T) {
 	repo.AddAccount(testAccount)
 
 	// Test successful deposit
-	if !atmSvc.Deposit("123456", 500) {
-		t.Error("Expected true for successful deposit, got false")
+	if err := atmSvc.Deposit("123456", 500, ""); err != nil {
+		t.Errorf("Expected no error for successful deposit, got %v", err)
 	}
 	if repo.GetBalance("123456") != 1500 {
 		t.Errorf("Expected balance of 1500 after deposit, got %d", repo.GetBalance("123456"))
 	}
+
+	// Test failed deposits due to invalid amounts or account
+	for _, amount := range []int{0, -10, 15, 2010} {
+		if err := atmSvc.Deposit("123456", amount, ""); err == nil {
+			t.Errorf("Expected error for deposit of %d, got nil", amount)
+		}
+	}
+	if err := atmSvc.Deposit("999999", 100, ""); err == nil {
+		t.Error("Expected error for deposit to unknown account, got nil")
+	}
+	if repo.GetBalance("123456") != 1500 {
+		t.Errorf("Expected balance of 1500 after failed deposits, got %d", repo.GetBalance("123456"))
+	}
+}
+
+func TestValidateDeposit(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	tests := []struct {
+		amount    int
+		expectErr bool
+	}{
+		{amount: 10, expectErr: false},
+		{amount: 2000, expectErr: false},
+		{amount: 0, expectErr: true},
+		{amount: -50, expectErr: true},
+		{amount: 25, expectErr: true},
+		{amount: 2010, expectErr: true},
+	}
+
+	for _, test := range tests {
+		err := atmSvc.ValidateDeposit(test.amount)
+		if (err != nil) != test.expectErr {
+			t.Errorf("For amount %d, expected error %v but got %v", test.amount, test.expectErr, err)
+		}
+	}
+}
+
+func TestConcurrentTransfers(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "111111", Balance: 1000})
+	repo.AddAccount(account_repository.Account{AccountNumber: "222222", Balance: 1000})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				atmSvc.Transfer("111111", "222222", 30, "")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				atmSvc.Transfer("222222", "111111", 20, "")
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := atmSvc.GetBalance("111111") + atmSvc.GetBalance("222222")
+	if total != 2000 {
+		t.Errorf("Expected total balance of 2000, got %d", total)
+	}
+	if atmSvc.GetBalance("111111") < 0 || atmSvc.GetBalance("222222") < 0 {
+		t.Errorf("Expected non-negative balances, got %d/%d", atmSvc.GetBalance("111111"), atmSvc.GetBalance("222222"))
+	}
+}
+
+func TestTransactionLedger(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	ledger := transaction_repository.NewTransactionRepository()
+	atmSvc := NewATMService(repo, ledger, config.Default())
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 500})
+	repo.AddAccount(account_repository.Account{AccountNumber: "987654", Balance: 100})
+
+	day := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	atmSvc.now = func() time.Time { return day }
+	atmSvc.Withdraw("123456", 100)
+	atmSvc.Withdraw("123456", 1000)
+
+	atmSvc.now = func() time.Time { return day.Add(24 * time.Hour) }
+	atmSvc.Transfer("123456", "987654", 50, "654321")
+	atmSvc.Deposit("123456", 20, "")
+
+	all := atmSvc.GetTransactions("123456", time.Time{}, time.Time{})
+	expected := []struct {
+		trxType transaction_repository.TransactionType
+		status  transaction_repository.TransactionStatus
+		balance int
+	}{
+		{transaction_repository.TypeWithdraw, transaction_repository.StatusSuccess, 400},
+		{transaction_repository.TypeWithdraw, transaction_repository.StatusFailed, 400},
+		{transaction_repository.TypeTransferOut, transaction_repository.StatusSuccess, 350},
+		{transaction_repository.TypeDeposit, transaction_repository.StatusSuccess, 370},
+	}
+	if len(all) != len(expected) {
+		t.Fatalf("Expected %d transactions, got %d", len(expected), len(all))
+	}
+	for i, trx := range all {
+		if trx.Type != expected[i].trxType || trx.Status != expected[i].status || trx.Balance != expected[i].balance {
+			t.Errorf("Expected %v, got %+v", expected[i], trx)
+		}
+	}
+	if all[1].Reason == "" {
+		t.Error("Expected failure reason on failed withdrawal, got empty")
+	}
+	if all[2].Counterparty != "987654" || all[2].Reference != "654321" {
+		t.Errorf("Expected counterparty and reference on transfer, got %+v", all[2])
+	}
+
+	firstDay := atmSvc.GetTransactions("123456", day, day.Add(24*time.Hour))
+	if len(firstDay) != 2 {
+		t.Errorf("Expected 2 transactions on the first day, got %d", len(firstDay))
+	}
+
+	incoming := atmSvc.GetTransactions("987654", time.Time{}, time.Time{})
+	if len(incoming) != 1 || incoming[0].Type != transaction_repository.TypeTransferIn || incoming[0].Balance != 150 {
+		t.Errorf("Expected one incoming transfer with balance 150, got %+v", incoming)
+	}
+}
+
+func TestMiniStatement(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 100})
+
+	if len(atmSvc.MiniStatement("123456", 3)) != 0 {
+		t.Error("Expected empty statement for a new account")
+	}
+
+	atmSvc.Deposit("123456", 10, "")
+	atmSvc.Withdraw("123456", 20)
+	atmSvc.Withdraw("123456", 5000)
+	atmSvc.Deposit("123456", 30, "")
+	atmSvc.Withdraw("123456", 40)
+
+	statement := atmSvc.MiniStatement("123456", 3)
+	expected := []int{20, 30, 40}
+	if len(statement) != len(expected) {
+		t.Fatalf("Expected %d transactions, got %d", len(expected), len(statement))
+	}
+	for i, trx := range statement {
+		if trx.Amount != expected[i] || trx.Status != transaction_repository.StatusSuccess {
+			t.Errorf("Expected successful transaction of %d, got %+v", expected[i], trx)
+		}
+	}
+	if statement[2].Balance != 80 {
+		t.Errorf("Expected running balance of 80, got %d", statement[2].Balance)
+	}
+}
+
+func TestBalanceInquiry(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Name: "John Doe", Balance: 250})
+
+	inquiry, err := atmSvc.BalanceInquiry("123456")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if inquiry.Name != "John Doe" || inquiry.AvailableBalance != 250 || inquiry.LedgerBalance != 250 {
+		t.Errorf("Expected John Doe with 250/250, got %+v", inquiry)
+	}
+
+	if _, err := atmSvc.BalanceInquiry("999999"); err == nil {
+		t.Error("Expected error for unknown account, got nil")
+	}
+}
+
+func TestChangePIN(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "135790", Pin: "111222", Balance: 100})
+
+	tests := []struct {
+		name       string
+		currentPin string
+		newPin     string
+		confirmPin string
+	}{
+		{name: "wrong current PIN", currentPin: "999999", newPin: "482915", confirmPin: "482915"},
+		{name: "too short", currentPin: "111222", newPin: "4829", confirmPin: "4829"},
+		{name: "not digits", currentPin: "111222", newPin: "48a915", confirmPin: "48a915"},
+		{name: "same as current", currentPin: "111222", newPin: "111222", confirmPin: "111222"},
+		{name: "same digit", currentPin: "111222", newPin: "777777", confirmPin: "777777"},
+		{name: "ascending", currentPin: "111222", newPin: "234567", confirmPin: "234567"},
+		{name: "descending", currentPin: "111222", newPin: "876543", confirmPin: "876543"},
+		{name: "account number", currentPin: "111222", newPin: "135790", confirmPin: "135790"},
+		{name: "confirmation mismatch", currentPin: "111222", newPin: "482915", confirmPin: "482916"},
+	}
+	for _, test := range tests {
+		if err := atmSvc.ChangePIN("135790", test.currentPin, test.newPin, test.confirmPin); err == nil {
+			t.Errorf("%s: Expected error, got nil", test.name)
+		}
+	}
+
+	acc := repo.FindAccount("135790")
+	if _, err := atmSvc.ValidatePIN(acc, "111222"); err != nil {
+		t.Errorf("Expected PIN to be unchanged, got %v", err)
+	}
+
+	if err := atmSvc.ChangePIN("135790", "111222", "482915", "482915"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	acc = repo.FindAccount("135790")
+	if _, err := atmSvc.ValidatePIN(acc, "482915"); err != nil {
+		t.Errorf("Expected new PIN to be valid, got %v", err)
+	}
+	if _, err := atmSvc.ValidatePIN(acc, "111222"); err == nil {
+		t.Error("Expected old PIN to be rejected, got nil")
+	}
+}
+
+func TestMigratePlaintextPINs(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "111111", Pin: "123123"})
+	repo.AddAccount(account_repository.Account{AccountNumber: "222222", Pin: "456456"})
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "333333", Pin: "789789"})
+
+	migrated, err := atmSvc.MigratePlaintextPINs()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if migrated != 2 {
+		t.Errorf("Expected 2 migrated accounts, got %d", migrated)
+	}
+
+	for number, pin := range map[string]string{"111111": "123123", "222222": "456456", "333333": "789789"} {
+		acc := repo.FindAccount(number)
+		if !hasher.IsHashed(acc.Pin) {
+			t.Errorf("Expected hashed PIN for %s, got %q", number, acc.Pin)
+		}
+		if _, err := atmSvc.ValidatePIN(acc, pin); err != nil {
+			t.Errorf("Expected PIN to verify for %s, got %v", number, err)
+		}
+	}
+
+	migrated, _ = atmSvc.MigratePlaintextPINs()
+	if migrated != 0 {
+		t.Errorf("Expected nothing left to migrate, got %d", migrated)
+	}
+}
+
+func TestPINLockout(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.MaxPINAttempts = 3
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "123456", Pin: "482915", Balance: 100})
+	acc := repo.FindAccount("123456")
+
+	// a successful login resets the counter
+	atmSvc.ValidatePIN(acc, "000000")
+	atmSvc.ValidatePIN(acc, "12")
+	if repo.FindAccount("123456").FailedPINAttempts != 2 {
+		t.Errorf("Expected 2 failed attempts, got %d", repo.FindAccount("123456").FailedPINAttempts)
+	}
+	if _, err := atmSvc.ValidatePIN(acc, "482915"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if repo.FindAccount("123456").FailedPINAttempts != 0 {
+		t.Errorf("Expected counter reset, got %d", repo.FindAccount("123456").FailedPINAttempts)
+	}
+
+	for i := 0; i < 3; i++ {
+		atmSvc.ValidatePIN(acc, "000000")
+	}
+	if !atmSvc.IsAccountBlocked("123456") {
+		t.Fatal("Expected account to be blocked")
+	}
+	if repo.FindAccount("123456").Status != account_repository.StatusBlocked {
+		t.Errorf("Expected blocked status stored on the account, got %q", repo.FindAccount("123456").Status)
+	}
+
+	// the right PIN no longer works once blocked
+	if validated, _ := atmSvc.ValidatePIN(acc, "482915"); validated != nil {
+		t.Error("Expected nil for blocked account, got account")
+	}
+
+	if err := atmSvc.UnblockAccount("123456"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if atmSvc.IsAccountBlocked("123456") {
+		t.Error("Expected account to be unblocked")
+	}
+	if validated, err := atmSvc.ValidatePIN(acc, "482915"); validated == nil {
+		t.Errorf("Expected valid account after unblock, got %v", err)
+	}
+
+	if err := atmSvc.UnblockAccount("123456"); err == nil {
+		t.Error("Expected error unblocking an active account, got nil")
+	}
+	if err := atmSvc.UnblockAccount("999999"); err == nil {
+		t.Error("Expected error unblocking an unknown account, got nil")
+	}
+}
+
+func TestAccountStatusEnforcement(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	statuses := map[string]account_repository.AccountStatus{
+		"100001": account_repository.StatusActive,
+		"100002": account_repository.StatusBlocked,
+		"100003": account_repository.StatusDormant,
+		"100004": account_repository.StatusClosed,
+	}
+	for number, status := range statuses {
+		repo.AddAccount(account_repository.Account{AccountNumber: number, Balance: 500, Status: status})
+	}
+	repo.AddAccount(account_repository.Account{AccountNumber: "100005", Balance: 500})
+
+	tests := []struct {
+		name     string
+		run      func() error
+		expected string
+	}{
+		{"login active", func() error { _, err := atmSvc.ValidateAccount("100001"); return err }, ""},
+		{"login legacy", func() error { _, err := atmSvc.ValidateAccount("100005"); return err }, ""},
+		{"login blocked", func() error { _, err := atmSvc.ValidateAccount("100002"); return err }, "account is blocked"},
+		{"login dormant", func() error { _, err := atmSvc.ValidateAccount("100003"); return err }, "account is dormant"},
+		{"login closed", func() error { _, err := atmSvc.ValidateAccount("100004"); return err }, "account is closed"},
+		{"withdraw blocked", func() error { _, err := atmSvc.Withdraw("100002", 10); return err }, "account is blocked"},
+		{"withdraw dormant", func() error { _, err := atmSvc.Withdraw("100003", 10); return err }, "account is dormant"},
+		{"withdraw closed", func() error { _, err := atmSvc.Withdraw("100004", 10); return err }, "account is closed"},
+		{"deposit blocked", func() error { return atmSvc.Deposit("100002", 10, "") }, ""},
+		{"deposit dormant", func() error { return atmSvc.Deposit("100003", 10, "") }, ""},
+		{"deposit closed", func() error { return atmSvc.Deposit("100004", 10, "") }, "account is closed"},
+		{"transfer from blocked", func() error { return atmSvc.Transfer("100002", "100001", 10, "") }, "account is blocked"},
+		{"transfer from dormant", func() error { return atmSvc.Transfer("100003", "100001", 10, "") }, "account is dormant"},
+		{"transfer from closed", func() error { return atmSvc.Transfer("100004", "100001", 10, "") }, "account is closed"},
+		{"transfer to blocked", func() error { return atmSvc.Transfer("100001", "100002", 10, "") }, ""},
+		{"transfer to dormant", func() error { return atmSvc.Transfer("100001", "100003", 10, "") }, ""},
+		{"transfer to closed", func() error { return atmSvc.Transfer("100001", "100004", 10, "") }, "destination account is closed"},
+		{"destination closed", func() error { _, err := atmSvc.ValidateTransferDestination("100001", "100004"); return err }, "destination account is closed"},
+		{"destination dormant", func() error { _, err := atmSvc.ValidateTransferDestination("100001", "100003"); return err }, ""},
+	}
+
+	for _, test := range tests {
+		err := test.run()
+		if test.expected == "" && err != nil {
+			t.Errorf("%s: Expected no error, got %v", test.name, err)
+		}
+		if test.expected != "" && (err == nil || err.Error() != test.expected) {
+			t.Errorf("%s: Expected %q, got %v", test.name, test.expected, err)
+		}
+	}
+
+	if repo.GetBalance("100004") != 500 {
+		t.Errorf("Expected closed account balance untouched, got %d", repo.GetBalance("100004"))
+	}
+}
+
+// closingStore closes every account just before it is updated, as if the
+// operator closed it after the service last looked at it.
+type closingStore struct {
+	*account_repository.AccountRepository
+}
+
+func (s *closingStore) UpdateAccount(number string, update func(acc *account_repository.Account) error) error {
+	s.AccountRepository.UpdateAccount(number, func(acc *account_repository.Account) error {
+		acc.Status = account_repository.StatusClosed
+		return nil
+	})
+	return s.AccountRepository.UpdateAccount(number, update)
+}
+
+func TestDepositChecksStatusWhenCrediting(t *testing.T) {
+	store := &closingStore{AccountRepository: account_repository.NewAccountRepository()}
+	atmSvc := NewATMService(store, transaction_repository.NewTransactionRepository(), config.Default())
+	store.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 500, Status: account_repository.StatusActive})
+
+	if err := atmSvc.Deposit("123456", 10, ""); err == nil || err.Error() != "account is closed" {
+		t.Errorf("Expected account is closed, got %v", err)
+	}
+	if store.GetBalance("123456") != 500 {
+		t.Errorf("Expected closed account balance untouched, got %d", store.GetBalance("123456"))
+	}
+}
+
+func TestSetAccountStatus(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "123456", Pin: "482915"})
+	if repo.FindAccount("123456").Status != account_repository.StatusActive {
+		t.Errorf("Expected new account to be active, got %q", repo.FindAccount("123456").Status)
+	}
+
+	if err := atmSvc.SetAccountStatus("123456", account_repository.StatusDormant); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := atmSvc.SetAccountStatus("123456", "frozen"); err == nil {
+		t.Error("Expected error for unknown status, got nil")
+	}
+	if err := atmSvc.SetAccountStatus("123456", account_repository.StatusClosed); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := atmSvc.SetAccountStatus("123456", account_repository.StatusActive); err == nil {
+		t.Error("Expected error reopening a closed account, got nil")
+	}
+}
+
+func TestConfiguredRules(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.AccountNumberLength = 8
+	cfg.PINLength = 4
+	cfg.NoteMultiple = 20
+	cfg.Withdraw.Max = 400
+	cfg.Transfer.Min = 5
+	cfg.Deposit.Max = 100
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "12345678", Pin: "4829", Balance: 1000})
+
+	acc, err := atmSvc.ValidateAccount("12345678")
+	if err != nil {
+		t.Fatalf("Expected 8-digit account number to be valid, got %v", err)
+	}
+	if _, err := atmSvc.ValidateAccount("123456"); err == nil {
+		t.Error("Expected error for 6-digit account number, got nil")
+	}
+	if _, err := atmSvc.ValidatePIN(acc, "4829"); err != nil {
+		t.Errorf("Expected 4-digit PIN to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"withdraw multiple", atmSvc.ValidateOtherWithdraw("12345678", 30), "invalid amount: must be a multiple of 20"},
+		{"withdraw max", atmSvc.ValidateOtherWithdraw("12345678", 420), "maximum amount to withdraw is $400"},
+		{"transfer min", atmSvc.ValidateTransferAmount("12345678", 4), "minimum amount to transfer is $5"},
+		{"deposit min", atmSvc.ValidateDeposit(0), "minimum amount to deposit is $20"},
+		{"deposit max", atmSvc.ValidateDeposit(120), "maximum amount to deposit is $100"},
+	}
+	for _, test := range tests {
+		if test.err == nil || test.err.Error() != test.expected {
+			t.Errorf("%s: Expected %q, got %v", test.name, test.expected, test.err)
+		}
+	}
+	if err := atmSvc.ValidateOtherWithdraw("12345678", 400); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// the rules hold without the validation screens too
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "87654321", Pin: "5937"})
+	if _, err := atmSvc.Withdraw("12345678", 30); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount for an odd withdrawal, got %v", err)
+	}
+	if _, err := atmSvc.Withdraw("12345678", 420); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded for a withdrawal above the maximum, got %v", err)
+	}
+	if err := atmSvc.Transfer("12345678", "87654321", 4, ""); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount for a transfer below the minimum, got %v", err)
+	}
+	if repo.GetBalance("12345678") != 1000 {
+		t.Errorf("Expected balance untouched, got %d", repo.GetBalance("12345678"))
+	}
 }
@@ -0,0 +1,167 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	atm_dispenser "atm-simulation-console/internal/atm/dispenser"
+	interbank_switch "atm-simulation-console/internal/interbank/switch"
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrInvalidAccount     = errors.New("invalid account number")
+	ErrInvalidPIN         = errors.New("invalid account number/PIN")
+	ErrInvalidCurrentPIN  = errors.New("invalid current PIN")
+	ErrWeakPIN            = errors.New("weak PIN")
+	ErrPINMismatch        = errors.New("new PIN confirmation does not match")
+	ErrSameAccount        = errors.New("cannot transfer to the same account")
+	ErrInvalidAmount      = errors.New("invalid amount")
+	ErrInsufficientFunds  = errors.New("insufficient balance")
+	ErrLimitExceeded      = errors.New("limit exceeded")
+	ErrAccountBlocked     = errors.New("account is blocked")
+	ErrAccountDormant     = errors.New("account is dormant")
+	ErrAccountClosed      = errors.New("account is closed")
+	ErrAccountNotBlocked  = errors.New("account is not blocked")
+	ErrInvalidStatus      = errors.New("invalid account status")
+	ErrNoDispenser        = errors.New("no cash dispenser configured")
+	ErrNoSwitch           = errors.New("interbank transfers are not available")
+	ErrInvalidOperatorPIN = errors.New("invalid operator PIN")
+	ErrOperatorLocked     = errors.New("operator card is locked")
+
+	// Dispenser errors are passed through as they are.
+	ErrOutOfCash       = atm_dispenser.ErrOutOfCash
+	ErrCannotDispense  = atm_dispenser.ErrCannotDispense
+	ErrUnknownCassette = atm_dispenser.ErrUnknownCassette
+	ErrInvalidCount    = atm_dispenser.ErrInvalidCount
+
+	// Switch errors are passed through as they are.
+	ErrUnknownBank      = interbank_switch.ErrUnknownBank
+	ErrUnknownReference = interbank_switch.ErrUnknownReference
+)
+
+// ValidationError reports a malformed field, e.g. a PIN with letters in it.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+type InvalidAccountError struct {
+	AccountNumber string
+	Destination   bool
+}
+
+func (e *InvalidAccountError) Error() string {
+	if e.Destination {
+		return "invalid destination account"
+	}
+	return ErrInvalidAccount.Error()
+}
+
+func (e *InvalidAccountError) Is(target error) bool {
+	return target == ErrInvalidAccount
+}
+
+type WeakPINError struct {
+	Reason string
+}
+
+func (e *WeakPINError) Error() string {
+	return "new PIN must " + e.Reason
+}
+
+func (e *WeakPINError) Is(target error) bool {
+	return target == ErrWeakPIN
+}
+
+type InvalidAmountError struct {
+	Amount int
+	Reason string
+}
+
+func (e *InvalidAmountError) Error() string {
+	return e.Reason
+}
+
+func (e *InvalidAmountError) Is(target error) bool {
+	return target == ErrInvalidAmount
+}
+
+type InsufficientFundsError struct {
+	Requested int
+	Available int
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return "insufficient balance $" + strconv.Itoa(e.Requested)
+}
+
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+// LimitExceededError reports an amount above a per-transaction maximum or,
+// when Daily is set, above what is left of the daily limit.
+type LimitExceededError struct {
+	Operation string
+	Limit     int
+	Requested int
+	Daily     bool
+	Remaining int
+}
+
+func (e *LimitExceededError) Error() string {
+	if e.Daily {
+		return "daily " + e.Operation + " limit of $" + strconv.Itoa(e.Limit) +
+			" exceeded: $" + strconv.Itoa(e.Remaining) + " remaining today"
+	}
+	return "maximum amount to " + e.Operation + " is $" + strconv.Itoa(e.Limit)
+}
+
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// pinBlockedReason is the Reason given for accounts blocked by wrong PINs.
+const pinBlockedReason = "too many wrong PIN attempts"
+
+// AccountStatusError reports an operation refused because of the account's
+// lifecycle status. It matches ErrAccountBlocked, ErrAccountDormant or
+// ErrAccountClosed depending on Status.
+type AccountStatusError struct {
+	AccountNumber string
+	Status        account_repository.AccountStatus
+	Destination   bool
+	Reason        string
+}
+
+func (e *AccountStatusError) Error() string {
+	msg := "account is " + string(e.Status)
+	if e.Destination {
+		msg = "destination " + msg
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *AccountStatusError) Is(target error) bool {
+	switch e.Status {
+	case account_repository.StatusBlocked:
+		return target == ErrAccountBlocked
+	case account_repository.StatusDormant:
+		return target == ErrAccountDormant
+	case account_repository.StatusClosed:
+		return target == ErrAccountClosed
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	"atm-simulation-console/internal/config"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "123456", Pin: "482915", Balance: 100})
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "654321", Pin: "482915", Balance: 100})
+	repo.AddAccount(account_repository.Account{AccountNumber: "111111", Status: account_repository.StatusClosed})
+	repo.AddAccount(account_repository.Account{AccountNumber: "222222", Status: account_repository.StatusDormant})
+	acc := repo.FindAccount("123456")
+
+	tests := []struct {
+		name     string
+		run      func() error
+		expected error
+	}{
+		{"short account number", func() error { _, err := atmSvc.ValidateAccount("123"); return err }, ErrInvalidInput},
+		{"unknown account", func() error { _, err := atmSvc.ValidateAccount("999999"); return err }, ErrInvalidAccount},
+		{"unknown destination", func() error { _, err := atmSvc.ValidateTransferDestination("123456", "999999"); return err }, ErrInvalidAccount},
+		{"same account", func() error { return atmSvc.Transfer("123456", "123456", 10, "") }, ErrSameAccount},
+		{"wrong PIN", func() error { _, err := atmSvc.ValidatePIN(acc, "000000"); return err }, ErrInvalidPIN},
+		{"wrong current PIN", func() error { return atmSvc.ChangePIN("123456", "000001", "193746", "193746") }, ErrInvalidCurrentPIN},
+		{"weak PIN", func() error { return atmSvc.ChangePIN("123456", "482915", "111111", "111111") }, ErrWeakPIN},
+		{"PIN mismatch", func() error { return atmSvc.ChangePIN("123456", "482915", "193746", "193747") }, ErrPINMismatch},
+		{"not a multiple of 10", func() error { return atmSvc.ValidateOtherWithdraw("123456", 15) }, ErrInvalidAmount},
+		{"withdraw limit", func() error { return atmSvc.ValidateOtherWithdraw("123456", 2000) }, ErrLimitExceeded},
+		{"insufficient funds", func() error { _, err := atmSvc.Withdraw("123456", 500); return err }, ErrInsufficientFunds},
+		{"closed destination", func() error { return atmSvc.Transfer("123456", "111111", 10, "") }, ErrAccountClosed},
+		{"dormant account", func() error { _, err := atmSvc.ValidateAccount("222222"); return err }, ErrAccountDormant},
+		{"not blocked", func() error { return atmSvc.UnblockAccount("123456") }, ErrAccountNotBlocked},
+		{"unblock unknown", func() error { return atmSvc.UnblockAccount("999999") }, ErrInvalidAccount},
+		{"invalid status", func() error { return atmSvc.SetAccountStatus("123456", "frozen") }, ErrInvalidStatus},
+		{"invalid number input", func() error {
+			_, err := atmSvc.GetInputNumber(bufio.NewReader(strings.NewReader("abc\n")))
+			return err
+		}, ErrInvalidInput},
+	}
+
+	for _, test := range tests {
+		err := test.run()
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: Expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestStructuredErrors(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.MaxPINAttempts = 1
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "123456", Pin: "482915", Balance: 30})
+
+	var fundsErr *InsufficientFundsError
+	_, err := atmSvc.Withdraw("123456", 50)
+	if !errors.As(err, &fundsErr) || fundsErr.Requested != 50 || fundsErr.Available != 30 {
+		t.Errorf("Expected InsufficientFundsError for 50 of 30, got %#v", err)
+	}
+	if err.Error() != "insufficient balance $50" {
+		t.Errorf("Expected message to be kept, got %q", err.Error())
+	}
+
+	var limitErr *LimitExceededError
+	err = atmSvc.ValidateTransferAmount("123456", 1500)
+	if !errors.As(err, &limitErr) || limitErr.Limit != 1000 || limitErr.Requested != 1500 || limitErr.Operation != "transfer" {
+		t.Errorf("Expected LimitExceededError for transfer of 1500, got %#v", err)
+	}
+
+	var validationErr *ValidationError
+	_, err = atmSvc.ValidateAccount("12a456")
+	if !errors.As(err, &validationErr) || validationErr.Field != "account number" {
+		t.Errorf("Expected ValidationError on account number, got %#v", err)
+	}
+
+	var statusErr *AccountStatusError
+	_, err = atmSvc.ValidatePIN(repo.FindAccount("123456"), "000000")
+	if !errors.As(err, &statusErr) || statusErr.Status != account_repository.StatusBlocked || statusErr.AccountNumber != "123456" {
+		t.Errorf("Expected AccountStatusError for blocked account, got %#v", err)
+	}
+	if !errors.Is(err, ErrAccountBlocked) || errors.Is(err, ErrAccountClosed) {
+		t.Errorf("Expected error to match only ErrAccountBlocked, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	"atm-simulation-console/internal/config"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"errors"
+	"math"
+	"slices"
+)
+
+// Fee returns what the account would be charged for moving amount through
+// channel now. The month's free transactions are used up first.
+func (s *ATMService) Fee(accNumber string, channel Channel, amount int) int {
+	acc := s.repo.FindAccount(accNumber)
+	if acc == nil {
+		acc = &account_repository.Account{}
+	}
+	return s.fee(acc, channel, amount)
+}
+
+func (s *ATMService) fee(acc *account_repository.Account, channel Channel, amount int) int {
+	schedule := s.feeSchedule(channel)
+	if s.usedThisMonth(acc, channel) < schedule.FreePerMonth {
+		return 0
+	}
+	return schedule.Fixed + int(math.Round(float64(amount)*schedule.Percent/100))
+}
+
+// EnsureFeeIncomeAccount opens the account fees are credited to if fees are
+// configured and it does not exist yet. It has no PIN, so nobody can log in
+// with it.
+func (s *ATMService) EnsureFeeIncomeAccount() error {
+	number := s.cfg.Fees.IncomeAccount
+	if !s.cfg.Fees.Enabled() || s.AccountExists(number) {
+		return nil
+	}
+	if !s.repo.AddAccount(account_repository.Account{
+		AccountNumber: number,
+		Name:          "Fee Income",
+		Status:        account_repository.StatusActive,
+	}) {
+		return errors.New("cannot open fee income account " + number)
+	}
+	return nil
+}
+
+func (s *ATMService) feeSchedule(channel Channel) config.FeeConfig {
+	if channel == ChannelTransfer {
+		return s.cfg.Fees.Transfer
+	}
+	return s.cfg.Fees.Withdraw
+}
+
+func (s *ATMService) usedThisMonth(acc *account_repository.Account, channel Channel) int {
+	usage := channel.usage(acc)
+	if !usage.Month.Equal(monthStart(s.now())) {
+		return 0
+	}
+	return usage.Count
+}
+
+// posting is what a debit left on the accounts, for its ledger entries.
+type posting struct {
+	// balance is the payer's balance after the amount, before the fee
+	balance       int
+	destBalance   int
+	fee           int
+	incomeBalance int
+}
+
+// withFee runs update on the accounts and the fee income account as one
+// repository operation. update makes the debit on accounts[0] and returns
+// its fee, which is then moved to the income account, so a debit is never
+// stored without its fee or the other way round.
+func (s *ATMService) withFee(numbers []string, update func(accounts []*account_repository.Account) (int, error)) (int, error) {
+	if !s.cfg.Fees.Enabled() {
+		return 0, s.updateAccounts(numbers, func(accounts []*account_repository.Account) error {
+			_, err := update(accounts)
+			return err
+		})
+	}
+
+	all := numbers
+	income := slices.Index(numbers, s.cfg.Fees.IncomeAccount)
+	if income < 0 {
+		all = append(slices.Clip(numbers), s.cfg.Fees.IncomeAccount)
+		income = len(numbers)
+	}
+	incomeBalance := 0
+	err := s.updateAccounts(all, func(accounts []*account_repository.Account) error {
+		fee, err := update(accounts[:len(numbers)])
+		if err != nil {
+			return err
+		}
+		accounts[0].Balance -= fee
+		accounts[income].Balance += fee
+		incomeBalance = accounts[income].Balance
+		return nil
+	})
+	return incomeBalance, err
+}
+
+// recordFee posts the fee of a debit as separate entries on the payer and
+// the income account.
+func (s *ATMService) recordFee(accNumber, ref string, p posting) {
+	if p.fee == 0 {
+		return
+	}
+
+	income := s.cfg.Fees.IncomeAccount
+	s.record(transaction_repository.Transaction{
+		AccountNumber: accNumber,
+		Type:          transaction_repository.TypeFee,
+		Amount:        p.fee,
+		Balance:       p.balance - p.fee,
+		Counterparty:  income,
+		Reference:     ref,
+	}, nil)
+	s.record(transaction_repository.Transaction{
+		AccountNumber: income,
+		Type:          transaction_repository.TypeFeeIncome,
+		Amount:        p.fee,
+		Balance:       p.incomeBalance,
+		Counterparty:  accNumber,
+		Reference:     ref,
+	}, nil)
+}
@@ -0,0 +1,149 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	"atm-simulation-console/internal/config"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newFeeService(t *testing.T) (*ATMService, *account_repository.AccountRepository, *transaction_repository.TransactionRepository) {
+	repo := account_repository.NewAccountRepository()
+	ledger := transaction_repository.NewTransactionRepository()
+	cfg := config.Default()
+	cfg.Fees = config.FeesConfig{
+		IncomeAccount: "900000",
+		Withdraw:      config.FeeConfig{Fixed: 2, FreePerMonth: 2},
+		Transfer:      config.FeeConfig{Fixed: 1, Percent: 1.5},
+	}
+	atmSvc := NewATMService(repo, ledger, cfg)
+	if err := atmSvc.EnsureFeeIncomeAccount(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 1000})
+	repo.AddAccount(account_repository.Account{AccountNumber: "654321", Balance: 1000})
+	return atmSvc, repo, ledger
+}
+
+func TestFee(t *testing.T) {
+	atmSvc, _, _ := newFeeService(t)
+	day := time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC)
+	atmSvc.now = func() time.Time { return day }
+
+	tests := []struct {
+		name     string
+		channel  Channel
+		amount   int
+		expected int
+	}{
+		{"transfer fixed and percent", ChannelTransfer, 100, 3},
+		{"transfer percent rounds", ChannelTransfer, 30, 1},
+		{"first free withdrawal", ChannelWithdraw, 100, 0},
+	}
+	for _, test := range tests {
+		if fee := atmSvc.Fee("123456", test.channel, test.amount); fee != test.expected {
+			t.Errorf("%s: Expected fee %d, got %d", test.name, test.expected, fee)
+		}
+	}
+
+	atmSvc.Withdraw("123456", 10)
+	atmSvc.Withdraw("123456", 10)
+	if fee := atmSvc.Fee("123456", ChannelWithdraw, 100); fee != 2 {
+		t.Errorf("Expected fee 2 once free withdrawals are used, got %d", fee)
+	}
+
+	// the quota starts over next month
+	atmSvc.now = func() time.Time { return day.Add(24 * time.Hour) }
+	if fee := atmSvc.Fee("123456", ChannelWithdraw, 100); fee != 0 {
+		t.Errorf("Expected free withdrawal in a new month, got fee %d", fee)
+	}
+}
+
+func TestFeesArePosted(t *testing.T) {
+	atmSvc, repo, ledger := newFeeService(t)
+
+	if err := atmSvc.Transfer("123456", "654321", 200, "000001"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 1 fixed + 1.5% of 200
+	if repo.GetBalance("123456") != 796 || repo.GetBalance("654321") != 1200 || repo.GetBalance("900000") != 4 {
+		t.Errorf("Expected balances 796/1200/4, got %d/%d/%d", repo.GetBalance("123456"), repo.GetBalance("654321"), repo.GetBalance("900000"))
+	}
+
+	trxs := ledger.FindByAccount("123456", time.Time{}, time.Time{})
+	if len(trxs) != 2 || trxs[1].Type != transaction_repository.TypeFee || trxs[1].Amount != 4 || trxs[1].Counterparty != "900000" || trxs[1].Reference != "000001" {
+		t.Errorf("Expected a FEE entry after the transfer, got %+v", trxs)
+	}
+	income := ledger.FindByAccount("900000", time.Time{}, time.Time{})
+	if len(income) != 1 || income[0].Type != transaction_repository.TypeFeeIncome || income[0].Amount != 4 || income[0].Balance != 4 {
+		t.Errorf("Expected a FEE_INCOME entry on the income account, got %+v", income)
+	}
+
+	// the fee must be covered too
+	if err := atmSvc.Transfer("123456", "654321", 790, "000002"); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds, got %v", err)
+	}
+	if repo.GetBalance("123456") != 796 {
+		t.Errorf("Expected balance to stay at 796, got %d", repo.GetBalance("123456"))
+	}
+
+	if mini := atmSvc.MiniStatement("123456", 5); len(mini) != 2 || mini[1].Type != transaction_repository.TypeFee {
+		t.Errorf("Expected the fee on the mini statement, got %+v", mini)
+	}
+	if report := atmSvc.TotalsReport(); report.Fees != 4 || report.Transfers != 1 {
+		t.Errorf("Expected $4 fees and 1 transfer in the totals, got %+v", report)
+	}
+}
+
+func TestFeeMovesWithTheDebit(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.Cassettes = nil
+	cfg.Fees = config.FeesConfig{
+		IncomeAccount: "900000",
+		Withdraw:      config.FeeConfig{Fixed: 2},
+		Transfer:      config.FeeConfig{Fixed: 1},
+	}
+	// the income account is never opened, so no fee can be credited
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 1000})
+	repo.AddAccount(account_repository.Account{AccountNumber: "654321", Balance: 1000})
+
+	if _, err := atmSvc.Withdraw("123456", 100); !errors.Is(err, ErrInvalidAccount) {
+		t.Errorf("Expected ErrInvalidAccount, got %v", err)
+	}
+	if err := atmSvc.Transfer("123456", "654321", 100, "000001"); !errors.Is(err, ErrInvalidAccount) {
+		t.Errorf("Expected ErrInvalidAccount, got %v", err)
+	}
+	if repo.GetBalance("123456") != 1000 || repo.GetBalance("654321") != 1000 {
+		t.Errorf("Expected balances untouched, got %d/%d", repo.GetBalance("123456"), repo.GetBalance("654321"))
+	}
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelWithdraw); remaining != cfg.Withdraw.DailyLimit {
+		t.Errorf("Expected the failed withdrawal not to count, got %d remaining", remaining)
+	}
+}
+
+func TestEnsureFeeIncomeAccount(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), config.Default())
+	if err := atmSvc.EnsureFeeIncomeAccount(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if atmSvc.AccountExists("900000") {
+		t.Error("Expected no fee income account when fees are off")
+	}
+
+	atmSvc, repo, _ = newFeeService(t)
+	acc := repo.FindAccount("900000")
+	if acc == nil || acc.Balance != 0 {
+		t.Fatalf("Expected an empty fee income account, got %+v", acc)
+	}
+	if _, err := atmSvc.ValidatePIN(acc, "000000"); err == nil {
+		t.Error("Expected login to the fee income account to fail")
+	}
+	if err := atmSvc.EnsureFeeIncomeAccount(); err != nil {
+		t.Errorf("Expected existing account to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	interbank_switch "atm-simulation-console/internal/interbank/switch"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"errors"
+)
+
+// pendingTransfer is an interbank transfer the switch has accepted but not
+// settled yet. Its hold stays on the account until then, so the transfer is
+// picked up again after a restart.
+type pendingTransfer struct {
+	trxID     string
+	accNumber string
+	hold      account_repository.Hold
+}
+
+// UseSwitch enables interbank transfers through sw.
+func (s *ATMService) UseSwitch(sw interbank_switch.Switch) {
+	s.interbank = sw
+}
+
+// Banks lists the banks the switch can reach, or nothing when interbank
+// transfers are not available.
+func (s *ATMService) Banks() []interbank_switch.Bank {
+	if s.interbank == nil {
+		return nil
+	}
+	return s.interbank.Banks()
+}
+
+// InterbankNameInquiry returns the name on an account at another bank so the
+// customer can check it before confirming.
+func (s *ATMService) InterbankNameInquiry(bankCode, accNumber string) (string, error) {
+	if s.interbank == nil {
+		return "", ErrNoSwitch
+	}
+	name, err := s.interbank.NameInquiry(bankCode, accNumber)
+	return name, switchError(err, accNumber)
+}
+
+// InterbankTransfer holds amount plus any fee on the source account and sends
+// the transfer to the switch. It returns PENDING until the switch settles the
+// transfer, when the hold is debited, or rejects it, when the hold is
+// released and the ledger entry reversed.
+func (s *ATMService) InterbankTransfer(srcNumber, bankCode, destNumber string, amount int, ref string) (interbank_switch.Status, error) {
+	trx := transaction_repository.Transaction{
+		AccountNumber: srcNumber,
+		Type:          transaction_repository.TypeInterbankOut,
+		Amount:        amount,
+		Counterparty:  bankCode + "-" + destNumber,
+		Reference:     ref,
+	}
+
+	held, balance, err := s.holdInterbank(srcNumber, amount, trx.Counterparty, ref)
+	if err != nil {
+		s.record(trx, err)
+		return interbank_switch.StatusRejected, err
+	}
+
+	acc := s.repo.FindAccount(srcNumber)
+	_, err = s.interbank.Transfer(interbank_switch.TransferRequest{
+		Reference:     ref,
+		SourceAccount: srcNumber,
+		SourceName:    acc.Name,
+		BankCode:      bankCode,
+		DestAccount:   destNumber,
+		Amount:        amount,
+	})
+	if err != nil {
+		if _, releaseErr := s.releaseHold(held); releaseErr != nil {
+			// the switch does not know the reference, so settling releases it
+			s.keepPending(held)
+		}
+		err = switchError(err, destNumber)
+		s.record(trx, err)
+		return interbank_switch.StatusRejected, err
+	}
+
+	trx.Status = transaction_repository.StatusPending
+	trx.Balance = balance
+	trx = s.record(trx, nil)
+
+	held.trxID = trx.ID
+	s.keepPending(held)
+	return s.settle(ref)
+}
+
+// InterbankStatus asks the switch for the transfer's status and applies the
+// outcome if it has settled or been rejected since.
+func (s *ATMService) InterbankStatus(ref string) (interbank_switch.Status, error) {
+	return s.settle(ref)
+}
+
+// SettleInterbankTransfers applies the outcome of every pending transfer the
+// switch has finished with.
+func (s *ATMService) SettleInterbankTransfers() {
+	s.mu.Lock()
+	refs := make([]string, 0, len(s.pending))
+	for ref := range s.pending {
+		refs = append(refs, ref)
+	}
+	s.mu.Unlock()
+
+	for _, ref := range refs {
+		s.settle(ref)
+	}
+}
+
+// restorePending picks up the transfers whose holds were saved before a
+// restart. Their ledger entries are gone, so they are recorded afresh when
+// the transfers settle.
+func (s *ATMService) restorePending() {
+	for _, acc := range s.repo.ListAccounts() {
+		for _, hold := range acc.Holds {
+			s.pending[hold.Reference] = pendingTransfer{accNumber: acc.AccountNumber, hold: hold}
+		}
+	}
+}
+
+func (s *ATMService) keepPending(p pendingTransfer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[p.hold.Reference] = p
+}
+
+// holdInterbank holds amount plus the fee on the account. It also returns
+// the account's ledger balance, which the hold leaves unchanged.
+func (s *ATMService) holdInterbank(srcNumber string, amount int, counterparty, ref string) (pendingTransfer, int, error) {
+	held := pendingTransfer{
+		accNumber: srcNumber,
+		hold:      account_repository.Hold{Reference: ref, Amount: amount, Counterparty: counterparty, At: s.now()},
+	}
+	if s.interbank == nil {
+		return held, 0, ErrNoSwitch
+	}
+	if err := s.checkAccountStatus(srcNumber, checkDebitStatus); err != nil {
+		return held, 0, err
+	}
+	if err := s.ValidateTransferAmount(srcNumber, amount); err != nil {
+		return held, 0, err
+	}
+
+	balance := 0
+	err := s.updateAccount(srcNumber, func(acc *account_repository.Account) error {
+		for _, hold := range acc.Holds {
+			if hold.Reference == ref {
+				return interbank_switch.ErrDuplicate
+			}
+		}
+		var err error
+		if held.hold.Fee, err = s.authorize(acc, ChannelTransfer, amount); err != nil {
+			return err
+		}
+		acc.Holds = append(acc.Holds, held.hold)
+		balance = acc.Balance
+		return nil
+	})
+	return held, balance, err
+}
+
+// releaseHold removes the transfer's hold and gives back the daily limit and
+// free transaction it used up. It returns the account's balance.
+func (s *ATMService) releaseHold(p pendingTransfer) (int, error) {
+	balance := 0
+	err := s.updateAccount(p.accNumber, func(acc *account_repository.Account) error {
+		if !removeHold(acc, p.hold.Reference) {
+			return ErrUnknownReference
+		}
+		s.unuse(acc, ChannelTransfer, p.hold.Amount, p.hold.At)
+		balance = acc.Balance
+		return nil
+	})
+	return balance, err
+}
+
+// settle takes the transfer out of the pending set while it asks the switch,
+// so two callers never apply the same outcome twice. A transfer whose
+// outcome cannot be applied yet stays pending and is tried again.
+func (s *ATMService) settle(ref string) (interbank_switch.Status, error) {
+	s.mu.Lock()
+	p, ok := s.pending[ref]
+	delete(s.pending, ref)
+	s.mu.Unlock()
+	if !ok {
+		return "", ErrUnknownReference
+	}
+	if s.interbank == nil {
+		s.keepPending(p)
+		return interbank_switch.StatusPending, ErrNoSwitch
+	}
+
+	status, err := s.interbank.Status(ref)
+	if errors.Is(err, interbank_switch.ErrUnknownReference) {
+		// a switch with no record of the transfer never moved the money
+		status, err = interbank_switch.StatusRejected, nil
+	}
+	if err != nil || status == interbank_switch.StatusPending {
+		s.keepPending(p)
+		return status, err
+	}
+
+	switch status {
+	case interbank_switch.StatusSettled:
+		err = s.completeTransfer(p)
+	case interbank_switch.StatusRejected:
+		err = s.reverseTransfer(p)
+	}
+	if errors.Is(err, ErrUnknownReference) {
+		// the hold is gone, so there is nothing left to apply
+		return "", err
+	}
+	if err != nil {
+		s.keepPending(p)
+		return interbank_switch.StatusPending, err
+	}
+	return status, nil
+}
+
+// completeTransfer debits the held amount and moves the held fee to the
+// income account in one step. The debit is a new ledger entry at settlement
+// time, so entries recorded while the transfer was pending keep their
+// balances; the pending entry is marked settled and points to it.
+func (s *ATMService) completeTransfer(p pendingTransfer) error {
+	posted := posting{fee: p.hold.Fee}
+	var err error
+	posted.incomeBalance, err = s.withFee([]string{p.accNumber}, func(accounts []*account_repository.Account) (int, error) {
+		acc := accounts[0]
+		if !removeHold(acc, p.hold.Reference) {
+			return 0, ErrUnknownReference
+		}
+		acc.Balance -= p.hold.Amount
+		posted.balance = acc.Balance
+		return p.hold.Fee, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	trx := p.entry()
+	trx.Balance = posted.balance
+	settled := s.record(trx, nil)
+	if p.trxID != "" {
+		s.ledger.Update(p.trxID, func(trx *transaction_repository.Transaction) {
+			trx.Status = transaction_repository.StatusSettled
+			trx.SettledBy = settled.ID
+		})
+	}
+	s.recordFee(p.accNumber, p.hold.Reference, posted)
+	return nil
+}
+
+// reverseTransfer releases the hold and marks the pending entry reversed,
+// or records a reversed entry if it was lost in a restart.
+func (s *ATMService) reverseTransfer(p pendingTransfer) error {
+	balance, err := s.releaseHold(p)
+	if err != nil {
+		return err
+	}
+
+	reverse := func(trx *transaction_repository.Transaction) {
+		trx.Status = transaction_repository.StatusReversed
+		trx.Reason = "rejected by beneficiary bank"
+	}
+	if p.trxID != "" && s.ledger.Update(p.trxID, reverse) == nil {
+		return nil
+	}
+	trx := p.entry()
+	trx.Balance = balance
+	reverse(&trx)
+	s.record(trx, nil)
+	return nil
+}
+
+// entry is a ledger entry for the transfer, without a status or balance.
+func (p pendingTransfer) entry() transaction_repository.Transaction {
+	return transaction_repository.Transaction{
+		AccountNumber: p.accNumber,
+		Type:          transaction_repository.TypeInterbankOut,
+		Amount:        p.hold.Amount,
+		Counterparty:  p.hold.Counterparty,
+		Reference:     p.hold.Reference,
+	}
+}
+
+// removeHold drops the hold for ref and reports whether there was one.
+func removeHold(acc *account_repository.Account, ref string) bool {
+	for i, hold := range acc.Holds {
+		if hold.Reference == ref {
+			acc.Holds = append(acc.Holds[:i], acc.Holds[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// switchError turns the switch's unknown account error into the service's
+// own.
+func switchError(err error, accNumber string) error {
+	if errors.Is(err, interbank_switch.ErrUnknownAccount) {
+		return &InvalidAccountError{AccountNumber: accNumber, Destination: true}
+	}
+	return err
+}
@@ -0,0 +1,283 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	"atm-simulation-console/internal/config"
+	interbank_switch "atm-simulation-console/internal/interbank/switch"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newInterbankService(t *testing.T) (*ATMService, *account_repository.AccountRepository, *transaction_repository.TransactionRepository, *time.Time) {
+	repo := account_repository.NewAccountRepository()
+	ledger := transaction_repository.NewTransactionRepository()
+	cfg := config.Default()
+	cfg.Fees.Transfer = config.FeeConfig{Fixed: 5}
+	atmSvc := NewATMService(repo, ledger, cfg)
+	if err := atmSvc.EnsureFeeIncomeAccount(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Name: "John Doe", Balance: 1000})
+
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	atmSvc.now = clock
+
+	sw := interbank_switch.NewStubSwitch(time.Minute)
+	sw.SetClock(clock)
+	sw.AddBank(interbank_switch.Bank{Code: "014", Name: "BCA"})
+	sw.AddAccount("014", "445566", "Andi Wijaya", false)
+	sw.AddAccount("014", "556677", "Dewi Lestari", true)
+	atmSvc.UseSwitch(sw)
+	return atmSvc, repo, ledger, &now
+}
+
+func TestInterbankNameInquiry(t *testing.T) {
+	atmSvc, _, _, _ := newInterbankService(t)
+
+	if name, err := atmSvc.InterbankNameInquiry("014", "445566"); err != nil || name != "Andi Wijaya" {
+		t.Errorf("Expected Andi Wijaya, got %q, %v", name, err)
+	}
+	if _, err := atmSvc.InterbankNameInquiry("014", "999999"); !errors.Is(err, ErrInvalidAccount) {
+		t.Errorf("Expected ErrInvalidAccount, got %v", err)
+	}
+	if _, err := atmSvc.InterbankNameInquiry("999", "445566"); !errors.Is(err, ErrUnknownBank) {
+		t.Errorf("Expected ErrUnknownBank, got %v", err)
+	}
+
+	noSwitch := NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), config.Default())
+	if _, err := noSwitch.InterbankNameInquiry("014", "445566"); !errors.Is(err, ErrNoSwitch) {
+		t.Errorf("Expected ErrNoSwitch, got %v", err)
+	}
+	if len(noSwitch.Banks()) != 0 {
+		t.Errorf("Expected no banks without a switch, got %v", noSwitch.Banks())
+	}
+}
+
+func TestInterbankTransferSettles(t *testing.T) {
+	atmSvc, repo, ledger, now := newInterbankService(t)
+
+	status, err := atmSvc.InterbankTransfer("123456", "014", "445566", 200, "000001")
+	if err != nil || status != interbank_switch.StatusPending {
+		t.Fatalf("Expected PENDING, got %s, %v", status, err)
+	}
+	inquiry, _ := atmSvc.BalanceInquiry("123456")
+	if inquiry.LedgerBalance != 1000 || inquiry.AvailableBalance != 795 {
+		t.Errorf("Expected ledger 1000 and available 795 while pending, got %+v", inquiry)
+	}
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelTransfer); remaining != 4800 {
+		t.Errorf("Expected pending transfer to count toward the daily limit, got %d left", remaining)
+	}
+	if _, err := atmSvc.Withdraw("123456", 800); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected held funds to be unavailable, got %v", err)
+	}
+
+	*now = now.Add(time.Minute)
+	if status, err := atmSvc.InterbankStatus("000001"); err != nil || status != interbank_switch.StatusSettled {
+		t.Fatalf("Expected SETTLED, got %s, %v", status, err)
+	}
+	acc := repo.FindAccount("123456")
+	if acc.Balance != 795 || acc.Held() != 0 || repo.GetBalance("900000") != 5 {
+		t.Errorf("Expected balance 795, nothing held and $5 fee income, got %+v, fee income %d", acc, repo.GetBalance("900000"))
+	}
+
+	trxs := ledger.FindByAccount("123456", time.Time{}, time.Time{})
+	if trxs[0].Status != transaction_repository.StatusSettled || trxs[0].Balance != 1000 || trxs[0].SettledBy == "" {
+		t.Errorf("Expected the pending entry marked settled, got %+v", trxs[0])
+	}
+	var settled transaction_repository.Transaction
+	for _, trx := range trxs {
+		if trx.ID == trxs[0].SettledBy {
+			settled = trx
+		}
+	}
+	if settled.Type != transaction_repository.TypeInterbankOut || settled.Status != transaction_repository.StatusSuccess || settled.Balance != 800 || settled.Counterparty != "014-445566" || !settled.Timestamp.Equal(*now) {
+		t.Errorf("Expected the debit posted as a new INTERBANK_OUT entry at settlement, got %+v", settled)
+	}
+	if _, err := atmSvc.InterbankStatus("000001"); !errors.Is(err, ErrUnknownReference) {
+		t.Errorf("Expected a settled transfer to be applied once, got %v", err)
+	}
+}
+
+func TestSettlementKeepsRunningBalance(t *testing.T) {
+	atmSvc, _, _, now := newInterbankService(t)
+
+	if _, err := atmSvc.InterbankTransfer("123456", "014", "445566", 200, "000001"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := atmSvc.Deposit("123456", 20, "000002"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	*now = now.Add(time.Minute)
+
+	trxs := atmSvc.MiniStatement("123456", 10)
+	var balances []int
+	for _, trx := range trxs {
+		balances = append(balances, trx.Balance)
+	}
+	if len(balances) != 3 || balances[0] != 1020 || balances[1] != 820 || balances[2] != 815 {
+		t.Errorf("Expected deposit, transfer and fee in settlement order, got %+v", trxs)
+	}
+}
+
+func TestInterbankTransferRejected(t *testing.T) {
+	atmSvc, repo, ledger, now := newInterbankService(t)
+
+	if _, err := atmSvc.InterbankTransfer("123456", "014", "556677", 300, "000001"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	*now = now.Add(time.Minute)
+	atmSvc.SettleInterbankTransfers()
+
+	acc := repo.FindAccount("123456")
+	if acc.Balance != 1000 || acc.Held() != 0 || repo.GetBalance("900000") != 0 {
+		t.Errorf("Expected the hold to be released without a fee, got %+v", acc)
+	}
+	trxs := ledger.FindByAccount("123456", time.Time{}, time.Time{})
+	if len(trxs) != 1 || trxs[0].Status != transaction_repository.StatusReversed {
+		t.Errorf("Expected a REVERSED entry, got %+v", trxs)
+	}
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelTransfer); remaining != 5000 {
+		t.Errorf("Expected reversed transfer not to count toward the limit, got %d left", remaining)
+	}
+
+	tests := []struct {
+		name     string
+		bankCode string
+		dest     string
+		amount   int
+		expected error
+	}{
+		{"unknown account", "014", "999999", 100, ErrInvalidAccount},
+		{"unknown bank", "999", "445566", 100, ErrUnknownBank},
+		{"over the maximum", "014", "445566", 2000, ErrLimitExceeded},
+		{"fee not covered", "014", "445566", 998, ErrInsufficientFunds},
+	}
+	for i, test := range tests {
+		ref := "00001" + string(rune('0'+i))
+		status, err := atmSvc.InterbankTransfer("123456", test.bankCode, test.dest, test.amount, ref)
+		if !errors.Is(err, test.expected) || status != interbank_switch.StatusRejected {
+			t.Errorf("%s: Expected REJECTED with %v, got %s, %v", test.name, test.expected, status, err)
+		}
+	}
+	if acc := repo.FindAccount("123456"); acc.Held() != 0 {
+		t.Errorf("Expected no hold left behind, got %d", acc.Held())
+	}
+}
+
+func TestPendingTransferSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	cfg := config.Default()
+	cfg.Fees.Transfer = config.FeeConfig{Fixed: 5}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	sw := interbank_switch.NewStubSwitch(time.Minute)
+	sw.SetClock(clock)
+	sw.AddBank(interbank_switch.Bank{Code: "014", Name: "BCA"})
+	sw.AddAccount("014", "445566", "Andi Wijaya", false)
+
+	start := func(sw interbank_switch.Switch) (*ATMService, *account_repository.FileAccountRepository, *transaction_repository.TransactionRepository) {
+		repo, err := account_repository.NewFileAccountRepository(path, 0)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		ledger := transaction_repository.NewTransactionRepository()
+		atmSvc := NewATMService(repo, ledger, cfg)
+		atmSvc.now = clock
+		atmSvc.UseSwitch(sw)
+		return atmSvc, repo, ledger
+	}
+
+	atmSvc, repo, _ := start(sw)
+	atmSvc.EnsureFeeIncomeAccount()
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Name: "John Doe", Balance: 1000})
+	atmSvc.AddAccount(account_repository.Account{AccountNumber: "654321", Name: "Jane Doe", Balance: 1000})
+	if _, err := atmSvc.InterbankTransfer("123456", "014", "445566", 200, "000001"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := atmSvc.InterbankTransfer("654321", "014", "445566", 100, "000002"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	repo.Close()
+
+	// the switch still knows 000001 after the restart and settles it
+	atmSvc, repo, ledger := start(sw)
+	now = now.Add(time.Minute)
+	if status, err := atmSvc.InterbankStatus("000001"); err != nil || status != interbank_switch.StatusSettled {
+		t.Errorf("Expected SETTLED, got %s, %v", status, err)
+	}
+	acc := repo.FindAccount("123456")
+	if acc.Balance != 795 || acc.Held() != 0 || repo.GetBalance("900000") != 5 {
+		t.Errorf("Expected the transfer and its fee debited after a restart, got %+v, fee income %d", acc, repo.GetBalance("900000"))
+	}
+	trxs := ledger.FindByAccount("123456", time.Time{}, time.Time{})
+	if len(trxs) != 2 || trxs[0].Type != transaction_repository.TypeInterbankOut || trxs[0].Status != transaction_repository.StatusSuccess || trxs[0].Reference != "000001" {
+		t.Errorf("Expected the settled transfer recorded again, got %+v", trxs)
+	}
+	repo.Close()
+
+	// a switch with no record of a transfer never moved the money
+	sw = interbank_switch.NewStubSwitch(time.Minute)
+	atmSvc, repo, ledger = start(sw)
+	defer repo.Close()
+	if status, err := atmSvc.InterbankStatus("000002"); err != nil || status != interbank_switch.StatusRejected {
+		t.Errorf("Expected REJECTED, got %s, %v", status, err)
+	}
+	if acc := repo.FindAccount("654321"); acc.Balance != 1000 || acc.Held() != 0 {
+		t.Errorf("Expected the hold released, got %+v", acc)
+	}
+	if trxs := ledger.FindByAccount("654321", time.Time{}, time.Time{}); len(trxs) != 1 || trxs[0].Status != transaction_repository.StatusReversed {
+		t.Errorf("Expected a REVERSED entry, got %+v", trxs)
+	}
+}
+
+// failingStore fails every update while fail is set.
+type failingStore struct {
+	*account_repository.AccountRepository
+	fail bool
+}
+
+func (s *failingStore) UpdateAccounts(numbers []string, update func(accounts []*account_repository.Account) error) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return s.AccountRepository.UpdateAccounts(numbers, update)
+}
+
+func TestSettleFailureKeepsTransferPending(t *testing.T) {
+	store := &failingStore{AccountRepository: account_repository.NewAccountRepository()}
+	cfg := config.Default()
+	cfg.Fees.Transfer = config.FeeConfig{Fixed: 5}
+	atmSvc := NewATMService(store, transaction_repository.NewTransactionRepository(), cfg)
+	atmSvc.EnsureFeeIncomeAccount()
+	store.AddAccount(account_repository.Account{AccountNumber: "123456", Name: "John Doe", Balance: 1000})
+
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	atmSvc.now = func() time.Time { return now }
+	sw := interbank_switch.NewStubSwitch(time.Minute)
+	sw.SetClock(atmSvc.now)
+	sw.AddBank(interbank_switch.Bank{Code: "014", Name: "BCA"})
+	sw.AddAccount("014", "445566", "Andi Wijaya", false)
+	atmSvc.UseSwitch(sw)
+
+	if _, err := atmSvc.InterbankTransfer("123456", "014", "445566", 200, "000001"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	store.fail = true
+	if status, err := atmSvc.InterbankStatus("000001"); err == nil || status != interbank_switch.StatusPending {
+		t.Errorf("Expected PENDING with an error, got %s, %v", status, err)
+	}
+
+	store.fail = false
+	if status, err := atmSvc.InterbankStatus("000001"); err != nil || status != interbank_switch.StatusSettled {
+		t.Errorf("Expected SETTLED on the next try, got %s, %v", status, err)
+	}
+	if acc := store.FindAccount("123456"); acc.Balance != 795 || acc.Held() != 0 {
+		t.Errorf("Expected the transfer debited once, got %+v", acc)
+	}
+}
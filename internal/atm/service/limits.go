@@ -0,0 +1,120 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	"time"
+)
+
+type Channel string
+
+const (
+	ChannelWithdraw Channel = "withdraw"
+	ChannelTransfer Channel = "transfer"
+)
+
+// RemainingDailyLimit returns how much the account can still move through
+// channel before the next cut-off.
+func (s *ATMService) RemainingDailyLimit(accNumber string, channel Channel) int {
+	acc := s.repo.FindAccount(accNumber)
+	if acc == nil {
+		return s.dailyLimit(channel)
+	}
+	return s.remainingToday(acc, channel)
+}
+
+// checkDailyLimit is a quick check for the confirmation screens. Debits
+// check the limit again with checkUsage while they hold the account.
+func (s *ATMService) checkDailyLimit(accNumber string, channel Channel, amount int) error {
+	acc := s.repo.FindAccount(accNumber)
+	if acc == nil {
+		return nil
+	}
+	return s.checkUsage(acc, channel, amount)
+}
+
+func (s *ATMService) checkUsage(acc *account_repository.Account, channel Channel, amount int) error {
+	remaining := s.remainingToday(acc, channel)
+	if amount > remaining {
+		return &LimitExceededError{
+			Operation: string(channel),
+			Limit:     s.dailyLimit(channel),
+			Requested: amount,
+			Daily:     true,
+			Remaining: remaining,
+		}
+	}
+	return nil
+}
+
+func (s *ATMService) remainingToday(acc *account_repository.Account, channel Channel) int {
+	remaining := s.dailyLimit(channel) - s.usedToday(acc, channel)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func (s *ATMService) dailyLimit(channel Channel) int {
+	if channel == ChannelTransfer {
+		return s.cfg.Transfer.DailyLimit
+	}
+	return s.cfg.Withdraw.DailyLimit
+}
+
+// usage returns the part of acc that counts what left it through c.
+func (c Channel) usage(acc *account_repository.Account) *account_repository.Usage {
+	if c == ChannelTransfer {
+		return &acc.TransferUsage
+	}
+	return &acc.WithdrawUsage
+}
+
+func (s *ATMService) usedToday(acc *account_repository.Account, channel Channel) int {
+	usage := channel.usage(acc)
+	if !usage.Day.Equal(s.limitWindowStart(s.now())) {
+		return 0
+	}
+	return usage.Amount
+}
+
+// use counts a debit of amount against the account's usage of channel,
+// starting over when a new limit day or month has begun since the last one.
+func (s *ATMService) use(acc *account_repository.Account, channel Channel, amount int) {
+	now := s.now()
+	usage := channel.usage(acc)
+	if day := s.limitWindowStart(now); !usage.Day.Equal(day) {
+		usage.Day, usage.Amount = day, 0
+	}
+	if month := monthStart(now); !usage.Month.Equal(month) {
+		usage.Month, usage.Count = month, 0
+	}
+	usage.Amount += amount
+	usage.Count++
+}
+
+// unuse takes back what use counted at for a debit that was reversed. A day
+// or month that is already over is left alone.
+func (s *ATMService) unuse(acc *account_repository.Account, channel Channel, amount int, at time.Time) {
+	usage := channel.usage(acc)
+	if usage.Day.Equal(s.limitWindowStart(at)) {
+		usage.Amount -= amount
+	}
+	if usage.Month.Equal(monthStart(at)) {
+		usage.Count--
+	}
+}
+
+// limitWindowStart returns the most recent daily cut-off at or before t.
+func (s *ATMService) limitWindowStart(t time.Time) time.Time {
+	year, month, day := t.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, t.Location()).Add(s.cfg.CutOff())
+	if t.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+func monthStart(t time.Time) time.Time {
+	year, month, _ := t.Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+}
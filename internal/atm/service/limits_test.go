@@ -0,0 +1,190 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	"atm-simulation-console/internal/config"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDailyWithdrawLimit(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.Withdraw.DailyLimit = 1500
+	cfg.Transfer.DailyLimit = 1000
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 5000})
+	repo.AddAccount(account_repository.Account{AccountNumber: "654321", Balance: 5000})
+
+	day := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	atmSvc.now = func() time.Time { return day }
+
+	if _, err := atmSvc.Withdraw("123456", 1000); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelWithdraw); remaining != 500 {
+		t.Errorf("Expected 500 remaining, got %d", remaining)
+	}
+
+	var limitErr *LimitExceededError
+	err := atmSvc.ValidateOtherWithdraw("123456", 1000)
+	if !errors.As(err, &limitErr) || !limitErr.Daily || limitErr.Remaining != 500 {
+		t.Errorf("Expected daily LimitExceededError with 500 remaining, got %v", err)
+	}
+	if _, err := atmSvc.Withdraw("123456", 600); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded, got %v", err)
+	}
+	if repo.GetBalance("123456") != 4000 {
+		t.Errorf("Expected balance of 4000, got %d", repo.GetBalance("123456"))
+	}
+
+	// failed attempts and other accounts do not count
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelWithdraw); remaining != 500 {
+		t.Errorf("Expected 500 remaining, got %d", remaining)
+	}
+	if remaining := atmSvc.RemainingDailyLimit("654321", ChannelWithdraw); remaining != 1500 {
+		t.Errorf("Expected 1500 remaining for another account, got %d", remaining)
+	}
+
+	// withdrawals do not use up the transfer limit
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelTransfer); remaining != 1000 {
+		t.Errorf("Expected 1000 transfer limit remaining, got %d", remaining)
+	}
+
+	atmSvc.now = func() time.Time { return day.Add(24 * time.Hour) }
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelWithdraw); remaining != 1500 {
+		t.Errorf("Expected limit reset the next day, got %d", remaining)
+	}
+}
+
+func TestDailyTransferLimit(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.Transfer.DailyLimit = 800
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 5000})
+	repo.AddAccount(account_repository.Account{AccountNumber: "654321", Balance: 0})
+
+	atmSvc.now = func() time.Time { return time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC) }
+
+	if err := atmSvc.Transfer("123456", "654321", 500, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := atmSvc.ValidateTransferAmount("123456", 400); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded, got %v", err)
+	}
+	if err := atmSvc.Transfer("123456", "654321", 400, ""); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded, got %v", err)
+	}
+	if err := atmSvc.Transfer("123456", "654321", 300, ""); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelTransfer); remaining != 0 {
+		t.Errorf("Expected 0 remaining, got %d", remaining)
+	}
+}
+
+func TestDailyLimitCutOff(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.Withdraw.DailyLimit = 1000
+	cfg.LimitCutOff = "18:00"
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 5000})
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at        time.Duration
+		withdraw  int
+		remaining int
+	}{
+		{at: 17 * time.Hour, withdraw: 600, remaining: 400},
+		// the cut-off has passed, so the 17:00 withdrawal no longer counts
+		{at: 18 * time.Hour, withdraw: 300, remaining: 700},
+		// still the same limit day after midnight
+		{at: 26 * time.Hour, withdraw: 200, remaining: 500},
+		{at: 42 * time.Hour, withdraw: 0, remaining: 1000},
+	}
+
+	for _, test := range tests {
+		atmSvc.now = func() time.Time { return day.Add(test.at) }
+		if test.withdraw > 0 {
+			if _, err := atmSvc.Withdraw("123456", test.withdraw); err != nil {
+				t.Fatalf("At %v: Expected no error, got %v", test.at, err)
+			}
+		}
+		if remaining := atmSvc.RemainingDailyLimit("123456", ChannelWithdraw); remaining != test.remaining {
+			t.Errorf("At %v: Expected %d remaining, got %d", test.at, test.remaining, remaining)
+		}
+	}
+}
+
+func TestUsageSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	cfg := config.Default()
+	cfg.Withdraw.DailyLimit = 1000
+	cfg.Fees = config.FeesConfig{IncomeAccount: "900000", Withdraw: config.FeeConfig{Fixed: 2, FreePerMonth: 1}}
+	day := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	repo, err := account_repository.NewFileAccountRepository(path, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+	atmSvc.now = func() time.Time { return day }
+	atmSvc.EnsureFeeIncomeAccount()
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 5000})
+	if _, err := atmSvc.Withdraw("123456", 600); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	repo.Close()
+
+	// a new process starts with an empty ledger
+	repo, err = account_repository.NewFileAccountRepository(path, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer repo.Close()
+	atmSvc = NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+	atmSvc.now = func() time.Time { return day.Add(time.Hour) }
+
+	if remaining := atmSvc.RemainingDailyLimit("123456", ChannelWithdraw); remaining != 400 {
+		t.Errorf("Expected 400 remaining after a restart, got %d", remaining)
+	}
+	if fee := atmSvc.Fee("123456", ChannelWithdraw, 100); fee != 2 {
+		t.Errorf("Expected the free withdrawal to stay used after a restart, got fee %d", fee)
+	}
+	if _, err := atmSvc.Withdraw("123456", 500); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+func TestConcurrentDebitsStayWithinDailyLimit(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.Cassettes = nil
+	cfg.Withdraw.DailyLimit = 1000
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 5000})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			atmSvc.Withdraw("123456", 100)
+		}()
+	}
+	wg.Wait()
+
+	if balance := repo.GetBalance("123456"); balance != 4000 {
+		t.Errorf("Expected exactly the daily limit withdrawn, got balance %d", balance)
+	}
+}
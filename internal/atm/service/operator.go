@@ -0,0 +1,141 @@
+package atm_service
+
+import (
+	atm_dispenser "atm-simulation-console/internal/atm/dispenser"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"atm-simulation-console/internal/util/hasher"
+	"errors"
+	"time"
+)
+
+// TotalsReport sums up what went through the machine since it was last
+// balanced. Transfers and fees count the customer side only, interbank
+// transfers once they settle.
+type TotalsReport struct {
+	Since       time.Time
+	Withdrawals int
+	Withdrawn   int
+	Deposits    int
+	Deposited   int
+	Transfers   int
+	Transferred int
+	Fees        int
+	Failed      int
+	Cassettes   []atm_dispenser.CassetteReport
+	CashLeft    int
+}
+
+// IsOperatorCard reports whether the card number is the maintenance card.
+func (s *ATMService) IsOperatorCard(card string) bool {
+	return s.cfg.Operator.Card != "" && card == s.cfg.Operator.Card
+}
+
+// CheckOperatorCard refuses an operator card that is also an account number,
+// which would turn that customer's card into a way into maintenance mode.
+func (s *ATMService) CheckOperatorCard() error {
+	if card := s.cfg.Operator.Card; card != "" && s.AccountExists(card) {
+		return errors.New("operator card " + card + " is an account number")
+	}
+	return nil
+}
+
+// VerifyOperatorPIN counts wrong operator PINs in a row across sessions, as
+// ValidatePIN does for customers. At the wrong PIN threshold the operator
+// card is locked until the machine is restarted.
+func (s *ATMService) VerifyOperatorPIN(pin string) error {
+	s.mu.Lock()
+	locked := s.operatorFailures >= s.cfg.MaxPINAttempts
+	s.mu.Unlock()
+	if locked {
+		return ErrOperatorLocked
+	}
+	if s.cfg.Operator.Card == "" {
+		return ErrInvalidOperatorPIN
+	}
+
+	ok := hasher.VerifyPIN(pin, s.cfg.Operator.PIN)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ok {
+		s.operatorFailures = 0
+		return nil
+	}
+	s.operatorFailures++
+	if s.operatorFailures >= s.cfg.MaxPINAttempts {
+		return ErrOperatorLocked
+	}
+	return ErrInvalidOperatorPIN
+}
+
+func (s *ATMService) CassetteReport() ([]atm_dispenser.CassetteReport, error) {
+	if s.dispenser == nil {
+		return nil, ErrNoDispenser
+	}
+	return s.dispenser.Report(), nil
+}
+
+func (s *ATMService) LoadCash(denomination, count int) error {
+	if s.dispenser == nil {
+		return ErrNoDispenser
+	}
+	return s.dispenser.Load(denomination, count)
+}
+
+func (s *ATMService) RemoveCash(denomination, count int) error {
+	if s.dispenser == nil {
+		return ErrNoDispenser
+	}
+	return s.dispenser.Remove(denomination, count)
+}
+
+func (s *ATMService) TotalsReport() TotalsReport {
+	s.mu.Lock()
+	report := TotalsReport{Since: s.balancedAt}
+	s.mu.Unlock()
+
+	for _, trx := range s.ledger.FindAll(report.Since, time.Time{}) {
+		if trx.Type == transaction_repository.TypeTransferIn || trx.Type == transaction_repository.TypeFeeIncome {
+			continue
+		}
+		switch trx.Status {
+		case transaction_repository.StatusPending, transaction_repository.StatusSettled:
+			// a settled transfer counts as the entry that posted it
+			continue
+		case transaction_repository.StatusFailed, transaction_repository.StatusReversed:
+			report.Failed++
+			continue
+		}
+		switch trx.Type {
+		case transaction_repository.TypeWithdraw:
+			report.Withdrawals++
+			report.Withdrawn += trx.Amount
+		case transaction_repository.TypeDeposit:
+			report.Deposits++
+			report.Deposited += trx.Amount
+		case transaction_repository.TypeTransferOut, transaction_repository.TypeInterbankOut:
+			report.Transfers++
+			report.Transferred += trx.Amount
+		case transaction_repository.TypeFee:
+			report.Fees += trx.Amount
+		}
+	}
+
+	if s.dispenser != nil {
+		report.Cassettes = s.dispenser.Report()
+		report.CashLeft = s.dispenser.Total()
+	}
+	return report
+}
+
+// ResetCounters balances the machine: the totals report and the cassette
+// counters start over from now.
+func (s *ATMService) ResetCounters() {
+	s.mu.Lock()
+	s.balancedAt = s.now()
+	s.mu.Unlock()
+
+	if s.dispenser != nil {
+		s.dispenser.ResetCounters()
+	}
+}
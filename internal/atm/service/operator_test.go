@@ -0,0 +1,164 @@
+package atm_service
+
+import (
+	account_repository "atm-simulation-console/internal/account/repository"
+	atm_dispenser "atm-simulation-console/internal/atm/dispenser"
+	"atm-simulation-console/internal/config"
+	transaction_repository "atm-simulation-console/internal/transaction/repository"
+	"atm-simulation-console/internal/util/hasher"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOperatorCard(t *testing.T) {
+	cfg := config.Default()
+	if cfg.Operator.Card != "" {
+		t.Errorf("Expected no operator card by default, got %q", cfg.Operator.Card)
+	}
+	cfg.Operator = config.OperatorConfig{Card: "999999", PIN: "909090"}
+	atmSvc := NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), cfg)
+
+	if !atmSvc.IsOperatorCard("999999") || atmSvc.IsOperatorCard("112233") {
+		t.Error("Expected only 999999 to be the operator card")
+	}
+	if atmSvc.VerifyOperatorPIN("909090") != nil || atmSvc.VerifyOperatorPIN("123123") == nil {
+		t.Error("Expected only 909090 to be the operator PIN")
+	}
+
+	hash, _ := hasher.HashPIN("4321")
+	cfg.Operator.PIN = hash
+	atmSvc = NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), cfg)
+	if err := atmSvc.VerifyOperatorPIN("4321"); err != nil {
+		t.Errorf("Expected hashed operator PIN to verify, got %v", err)
+	}
+
+	cfg.Operator = config.OperatorConfig{}
+	atmSvc = NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), cfg)
+	if atmSvc.IsOperatorCard("") || atmSvc.VerifyOperatorPIN("") == nil {
+		t.Error("Expected maintenance mode to be disabled without an operator card")
+	}
+}
+
+func TestOperatorCardClashesWithAccount(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	repo.AddAccount(account_repository.Account{AccountNumber: "112233"})
+	cfg := config.Default()
+	cfg.Operator = config.OperatorConfig{Card: "112233", PIN: "909090"}
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	if err := atmSvc.CheckOperatorCard(); err == nil {
+		t.Error("Expected an operator card matching an account to be refused")
+	}
+
+	cfg.Operator.Card = "999999"
+	atmSvc = NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+	if err := atmSvc.CheckOperatorCard(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if atmSvc.AddAccount(account_repository.Account{AccountNumber: "999999", Pin: "482915"}) {
+		t.Error("Expected an account with the operator card number to be refused")
+	}
+}
+
+func TestOperatorPINLockout(t *testing.T) {
+	cfg := config.Default()
+	cfg.Operator = config.OperatorConfig{Card: "999999", PIN: "909090"}
+	atmSvc := NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), cfg)
+
+	// a correct PIN starts the count over
+	atmSvc.VerifyOperatorPIN("000000")
+	atmSvc.VerifyOperatorPIN("000000")
+	if err := atmSvc.VerifyOperatorPIN("909090"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for i := 1; i < cfg.MaxPINAttempts; i++ {
+		if err := atmSvc.VerifyOperatorPIN("000000"); !errors.Is(err, ErrInvalidOperatorPIN) {
+			t.Errorf("Attempt %d: Expected ErrInvalidOperatorPIN, got %v", i, err)
+		}
+	}
+	if err := atmSvc.VerifyOperatorPIN("000000"); !errors.Is(err, ErrOperatorLocked) {
+		t.Errorf("Expected ErrOperatorLocked, got %v", err)
+	}
+	if err := atmSvc.VerifyOperatorPIN("909090"); !errors.Is(err, ErrOperatorLocked) {
+		t.Errorf("Expected the right PIN to stay locked out, got %v", err)
+	}
+}
+
+func TestTotalsReport(t *testing.T) {
+	repo := account_repository.NewAccountRepository()
+	cfg := config.Default()
+	cfg.Cassettes = []config.CassetteConfig{{Denomination: 50, Count: 4}, {Denomination: 20, Count: 5}}
+	atmSvc := NewATMService(repo, transaction_repository.NewTransactionRepository(), cfg)
+
+	repo.AddAccount(account_repository.Account{AccountNumber: "123456", Balance: 1000})
+	repo.AddAccount(account_repository.Account{AccountNumber: "654321", Balance: 1000})
+
+	day := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	atmSvc.now = func() time.Time { return day }
+	atmSvc.ResetCounters()
+
+	atmSvc.Withdraw("123456", 120)
+	atmSvc.Withdraw("123456", 30)
+	atmSvc.Deposit("123456", 200, "000001")
+	atmSvc.Transfer("123456", "654321", 75, "000002")
+	if err := atmSvc.LoadCash(20, 10); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := atmSvc.RemoveCash(50, 1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	report := atmSvc.TotalsReport()
+	expected := TotalsReport{
+		Since:       day,
+		Withdrawals: 1,
+		Withdrawn:   120,
+		Deposits:    1,
+		Deposited:   200,
+		Transfers:   1,
+		Transferred: 75,
+		Failed:      1,
+		Cassettes: []atm_dispenser.CassetteReport{
+			{Denomination: 50, Count: 1, Dispensed: 2, Removed: 1},
+			{Denomination: 20, Count: 14, Dispensed: 1, Loaded: 10},
+		},
+		CashLeft: 330,
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, report)
+	}
+
+	atmSvc.now = func() time.Time { return day.Add(time.Hour) }
+	atmSvc.ResetCounters()
+	report = atmSvc.TotalsReport()
+	if report.Withdrawals != 0 || report.Failed != 0 || report.Cassettes[0].Dispensed != 0 {
+		t.Errorf("Expected totals to start over after reset, got %+v", report)
+	}
+	if report.CashLeft != 330 {
+		t.Errorf("Expected reset to keep the cash, got %d", report.CashLeft)
+	}
+}
+
+func TestCashManagementErrors(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cassettes = nil
+	atmSvc := NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), cfg)
+
+	if err := atmSvc.LoadCash(50, 1); !errors.Is(err, ErrNoDispenser) {
+		t.Errorf("Expected ErrNoDispenser, got %v", err)
+	}
+	if _, err := atmSvc.CassetteReport(); !errors.Is(err, ErrNoDispenser) {
+		t.Errorf("Expected ErrNoDispenser, got %v", err)
+	}
+
+	atmSvc = NewATMService(account_repository.NewAccountRepository(), transaction_repository.NewTransactionRepository(), config.Default())
+	if err := atmSvc.LoadCash(5, 1); !errors.Is(err, ErrUnknownCassette) {
+		t.Errorf("Expected ErrUnknownCassette, got %v", err)
+	}
+	if err := atmSvc.RemoveCash(100, 1000); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("Expected ErrInvalidCount, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+type Config struct {
+	AccountNumberLength int            `json:"account_number_length"`
+	PINLength           int            `json:"pin_length"`
+	NoteMultiple        int            `json:"note_multiple"`
+	MaxPINAttempts      int            `json:"max_pin_attempts"`
+	MiniStatementSize   int            `json:"mini_statement_size"`
+	LimitCutOff         string         `json:"limit_cutoff"`
+	Withdraw            WithdrawConfig `json:"withdraw"`
+	Transfer            TransferConfig `json:"transfer"`
+	Deposit             DepositConfig  `json:"deposit"`
+	// Cassettes loaded into the cash dispenser. An empty list means the
+	// machine never runs out of cash.
+	Cassettes []CassetteConfig `json:"cassettes"`
+	// Operator is the maintenance card. An empty card disables maintenance
+	// mode from the card reader.
+	Operator OperatorConfig `json:"operator"`
+	Fees     FeesConfig     `json:"fees"`
+	// Greeting is shown on the idle screen between customers.
+	Greeting string `json:"greeting"`
+}
+
+type WithdrawConfig struct {
+	Max        int   `json:"max"`
+	DailyLimit int   `json:"daily_limit"`
+	FastCash   []int `json:"fast_cash"`
+}
+
+type TransferConfig struct {
+	Min        int `json:"min"`
+	Max        int `json:"max"`
+	DailyLimit int `json:"daily_limit"`
+}
+
+type DepositConfig struct {
+	Max int `json:"max"`
+}
+
+// OperatorConfig holds the maintenance card number and its PIN, either in
+// plaintext or as a hash from the hasher package.
+type OperatorConfig struct {
+	Card string `json:"card"`
+	PIN  string `json:"pin"`
+}
+
+// FeesConfig sets the fee schedule per transaction type. Fees are credited to
+// IncomeAccount.
+type FeesConfig struct {
+	IncomeAccount string    `json:"income_account"`
+	Withdraw      FeeConfig `json:"withdraw"`
+	Transfer      FeeConfig `json:"transfer"`
+}
+
+// FeeConfig charges Fixed plus Percent of the amount, rounded to the nearest
+// dollar, once the first FreePerMonth transactions of the month are used up.
+type FeeConfig struct {
+	Fixed        int     `json:"fixed"`
+	Percent      float64 `json:"percent"`
+	FreePerMonth int     `json:"free_per_month"`
+}
+
+func (f FeeConfig) charged() bool {
+	return f.Fixed > 0 || f.Percent > 0
+}
+
+// Enabled reports whether any transaction type carries a fee.
+func (f FeesConfig) Enabled() bool {
+	return f.Withdraw.charged() || f.Transfer.charged()
+}
+
+type CassetteConfig struct {
+	Denomination int `json:"denomination"`
+	Count        int `json:"count"`
+}
+
+// Default returns the rules the simulator ships with.
+func Default() Config {
+	return Config{
+		AccountNumberLength: 6,
+		PINLength:           6,
+		NoteMultiple:        10,
+		MaxPINAttempts:      3,
+		MiniStatementSize:   5,
+		LimitCutOff:         "00:00",
+		Withdraw: WithdrawConfig{
+			Max:        1000,
+			DailyLimit: 2000,
+			FastCash:   []int{10, 50, 100},
+		},
+		Transfer: TransferConfig{
+			Min:        1,
+			Max:        1000,
+			DailyLimit: 5000,
+		},
+		Deposit: DepositConfig{
+			Max: 2000,
+		},
+		Cassettes: []CassetteConfig{
+			{Denomination: 100, Count: 50},
+			{Denomination: 50, Count: 100},
+			{Denomination: 20, Count: 200},
+			{Denomination: 10, Count: 200},
+		},
+		Fees: FeesConfig{
+			IncomeAccount: "900000",
+		},
+		Greeting: "Welcome! Please insert your card.",
+	}
+}
+
+// Load reads a JSON config file on top of the defaults, so a file only needs
+// the settings it changes. Unknown keys are rejected to catch typos.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate reports every rule the config breaks, not just the first one.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.AccountNumberLength >= 4 && c.AccountNumberLength <= 12, "account_number_length must be between 4 and 12, got %d", c.AccountNumberLength)
+	check(c.PINLength >= 4 && c.PINLength <= 12, "pin_length must be between 4 and 12, got %d", c.PINLength)
+	check(c.NoteMultiple > 0, "note_multiple must be positive, got %d", c.NoteMultiple)
+	check(c.MaxPINAttempts > 0, "max_pin_attempts must be positive, got %d", c.MaxPINAttempts)
+	check(c.MiniStatementSize > 0, "mini_statement_size must be positive, got %d", c.MiniStatementSize)
+	_, err := time.Parse("15:04", c.LimitCutOff)
+	check(err == nil, "limit_cutoff must be a time of day as HH:MM, got %q", c.LimitCutOff)
+
+	check(c.Withdraw.Max > 0, "withdraw.max must be positive, got %d", c.Withdraw.Max)
+	check(c.Withdraw.DailyLimit > 0, "withdraw.daily_limit must be positive, got %d", c.Withdraw.DailyLimit)
+	check(len(c.Withdraw.FastCash) > 0 && len(c.Withdraw.FastCash) <= 6, "withdraw.fast_cash must have between 1 and 6 amounts, got %d", len(c.Withdraw.FastCash))
+	for i, amount := range c.Withdraw.FastCash {
+		check(amount > 0 && amount <= c.Withdraw.Max, "withdraw.fast_cash[%d] must be between 1 and withdraw.max %d, got %d", i, c.Withdraw.Max, amount)
+		if c.NoteMultiple > 0 {
+			check(amount%c.NoteMultiple == 0, "withdraw.fast_cash[%d] must be a multiple of note_multiple %d, got %d", i, c.NoteMultiple, amount)
+		}
+	}
+
+	check(c.Transfer.Min > 0, "transfer.min must be positive, got %d", c.Transfer.Min)
+	check(c.Transfer.Max >= c.Transfer.Min, "transfer.max must be at least transfer.min %d, got %d", c.Transfer.Min, c.Transfer.Max)
+	check(c.Transfer.DailyLimit > 0, "transfer.daily_limit must be positive, got %d", c.Transfer.DailyLimit)
+
+	check(c.Deposit.Max >= c.NoteMultiple, "deposit.max must be at least note_multiple %d, got %d", c.NoteMultiple, c.Deposit.Max)
+
+	seen := make(map[int]bool)
+	for i, cassette := range c.Cassettes {
+		check(cassette.Denomination > 0, "cassettes[%d].denomination must be positive, got %d", i, cassette.Denomination)
+		if c.NoteMultiple > 0 {
+			check(cassette.Denomination%c.NoteMultiple == 0, "cassettes[%d].denomination must be a multiple of note_multiple %d, got %d", i, c.NoteMultiple, cassette.Denomination)
+		}
+		check(!seen[cassette.Denomination], "cassettes[%d].denomination %d is listed twice", i, cassette.Denomination)
+		check(cassette.Count >= 0, "cassettes[%d].count must not be negative, got %d", i, cassette.Count)
+		seen[cassette.Denomination] = true
+	}
+
+	if c.Operator.Card != "" {
+		check(len(c.Operator.Card) == c.AccountNumberLength && isDigits(c.Operator.Card), "operator.card must be %d digits, got %q", c.AccountNumberLength, c.Operator.Card)
+		check(c.Operator.PIN != "", "operator.pin must be set when operator.card is")
+	}
+
+	fees := []struct {
+		name string
+		fee  FeeConfig
+	}{{"withdraw", c.Fees.Withdraw}, {"transfer", c.Fees.Transfer}}
+	for _, f := range fees {
+		name, fee := f.name, f.fee
+		check(fee.Fixed >= 0, "fees.%s.fixed must not be negative, got %d", name, fee.Fixed)
+		check(fee.Percent >= 0 && fee.Percent <= 100, "fees.%s.percent must be between 0 and 100, got %g", name, fee.Percent)
+		check(fee.FreePerMonth >= 0, "fees.%s.free_per_month must not be negative, got %d", name, fee.FreePerMonth)
+	}
+	if c.Fees.Enabled() {
+		check(len(c.Fees.IncomeAccount) == c.AccountNumberLength && isDigits(c.Fees.IncomeAccount), "fees.income_account must be %d digits, got %q", c.AccountNumberLength, c.Fees.IncomeAccount)
+		check(c.Fees.IncomeAccount != c.Operator.Card, "fees.income_account must differ from operator.card")
+	}
+
+	return errors.Join(errs...)
+}
+
+// CutOff returns LimitCutOff as an offset from midnight.
+func (c Config) CutOff() time.Duration {
+	t, err := time.Parse("15:04", c.LimitCutOff)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
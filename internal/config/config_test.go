@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("expected default config to be valid, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{
+		"pin_length": 4,
+		"note_multiple": 20,
+		"limit_cutoff": "18:30",
+		"withdraw": {"max": 500, "daily_limit": 1000, "fast_cash": [20, 40, 100, 200]},
+		"cassettes": [{"denomination": 100, "count": 10}, {"denomination": 20, "count": 50}]
+	}`), 0o600)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.PINLength != 4 || cfg.NoteMultiple != 20 || len(cfg.Withdraw.FastCash) != 4 || len(cfg.Cassettes) != 2 {
+		t.Errorf("expected overrides to be applied, got %+v", cfg)
+	}
+	if cfg.AccountNumberLength != 6 || cfg.Transfer.Max != 1000 {
+		t.Errorf("expected defaults for missing keys, got %+v", cfg)
+	}
+	if cfg.CutOff() != 18*time.Hour+30*time.Minute {
+		t.Errorf("expected 18h30m cut-off, got %v", cfg.CutOff())
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{name: "malformed", content: `{"pin_length": `, expected: []string{"parse"}},
+		{name: "unknown key", content: `{"pin_lenght": 4}`, expected: []string{"pin_lenght"}},
+		{
+			name:    "invalid rules",
+			content: `{"pin_length": 2, "limit_cutoff": "25:00", "withdraw": {"max": 100, "daily_limit": 100, "fast_cash": [15, 500]}}`,
+			expected: []string{
+				"pin_length must be between 4 and 12, got 2",
+				"limit_cutoff",
+				"withdraw.fast_cash[0] must be a multiple of note_multiple 10, got 15",
+				"withdraw.fast_cash[1] must be between 1 and withdraw.max 100, got 500",
+			},
+		},
+		{
+			name:    "invalid cassettes",
+			content: `{"cassettes": [{"denomination": 25, "count": 10}, {"denomination": 50, "count": -1}, {"denomination": 50, "count": 1}]}`,
+			expected: []string{
+				"cassettes[0].denomination must be a multiple of note_multiple 10, got 25",
+				"cassettes[1].count must not be negative, got -1",
+				"cassettes[2].denomination 50 is listed twice",
+			},
+		},
+		{
+			name:     "invalid operator",
+			content:  `{"operator": {"card": "99x999", "pin": ""}}`,
+			expected: []string{`operator.card must be 6 digits, got "99x999"`, "operator.pin must be set"},
+		},
+		{
+			name:    "invalid fees",
+			content: `{"fees": {"income_account": "", "withdraw": {"fixed": -1}, "transfer": {"percent": 150, "free_per_month": -2}}}`,
+			expected: []string{
+				"fees.withdraw.fixed must not be negative, got -1",
+				"fees.transfer.percent must be between 0 and 100, got 150",
+				"fees.transfer.free_per_month must not be negative, got -2",
+				`fees.income_account must be 6 digits, got ""`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "config.json")
+		os.WriteFile(path, []byte(test.content), 0o600)
+
+		_, err := Load(path)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", test.name)
+			continue
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("%s: expected error to mention %q, got %v", test.name, expected, err)
+			}
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected error for missing file, got nil")
+	}
+}
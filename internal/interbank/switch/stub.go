@@ -0,0 +1,154 @@
+package interbank_switch
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type stubAccount struct {
+	name    string
+	rejects bool
+}
+
+type stubTransfer struct {
+	request  TransferRequest
+	accepted time.Time
+	status   Status
+}
+
+// StubSwitch is an in-process Switch for the simulator. Accepted transfers
+// settle once SettleAfter has passed, except to accounts added with rejects
+// set, which the beneficiary bank turns down.
+type StubSwitch struct {
+	mu          sync.Mutex
+	banks       map[string]Bank
+	accounts    map[string]map[string]stubAccount
+	transfers   map[string]*stubTransfer
+	settleAfter time.Duration
+	now         func() time.Time
+}
+
+func NewStubSwitch(settleAfter time.Duration) *StubSwitch {
+	return &StubSwitch{
+		banks:       make(map[string]Bank),
+		accounts:    make(map[string]map[string]stubAccount),
+		transfers:   make(map[string]*stubTransfer),
+		settleAfter: settleAfter,
+		now:         time.Now,
+	}
+}
+
+// NewSampleSwitch returns a stub with a few banks and accounts to try, one of
+// which rejects every transfer.
+func NewSampleSwitch() *StubSwitch {
+	s := NewStubSwitch(10 * time.Second)
+	s.AddBank(Bank{Code: "008", Name: "Mandiri"})
+	s.AddBank(Bank{Code: "009", Name: "BNI"})
+	s.AddBank(Bank{Code: "014", Name: "BCA"})
+	s.AddAccount("008", "223344", "Budi Santoso", false)
+	s.AddAccount("009", "334455", "Siti Rahma", false)
+	s.AddAccount("014", "445566", "Andi Wijaya", false)
+	s.AddAccount("014", "556677", "Dewi Lestari", true)
+	return s
+}
+
+// SetClock replaces the clock settlement times are measured with.
+func (s *StubSwitch) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+func (s *StubSwitch) AddBank(bank Bank) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.banks[bank.Code] = bank
+	if s.accounts[bank.Code] == nil {
+		s.accounts[bank.Code] = make(map[string]stubAccount)
+	}
+}
+
+// AddAccount opens an account at a bank added with AddBank. Transfers to it
+// are rejected at settlement when rejects is set.
+func (s *StubSwitch) AddAccount(bankCode, accNumber, name string, rejects bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if accounts, ok := s.accounts[bankCode]; ok {
+		accounts[accNumber] = stubAccount{name: name, rejects: rejects}
+	}
+}
+
+func (s *StubSwitch) Banks() []Bank {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	banks := make([]Bank, 0, len(s.banks))
+	for _, bank := range s.banks {
+		banks = append(banks, bank)
+	}
+	sort.Slice(banks, func(i, j int) bool {
+		return banks[i].Code < banks[j].Code
+	})
+	return banks
+}
+
+func (s *StubSwitch) NameInquiry(bankCode, accNumber string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.find(bankCode, accNumber)
+	if err != nil {
+		return "", err
+	}
+	return account.name, nil
+}
+
+func (s *StubSwitch) Transfer(req TransferRequest) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.transfers[req.Reference]; ok {
+		return "", ErrDuplicate
+	}
+	if _, err := s.find(req.BankCode, req.DestAccount); err != nil {
+		return StatusRejected, err
+	}
+
+	s.transfers[req.Reference] = &stubTransfer{request: req, accepted: s.now(), status: StatusPending}
+	return StatusPending, nil
+}
+
+func (s *StubSwitch) Status(reference string) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transfer, ok := s.transfers[reference]
+	if !ok {
+		return "", ErrUnknownReference
+	}
+	if transfer.status == StatusPending && !s.now().Before(transfer.accepted.Add(s.settleAfter)) {
+		transfer.status = StatusSettled
+		if s.accounts[transfer.request.BankCode][transfer.request.DestAccount].rejects {
+			transfer.status = StatusRejected
+		}
+	}
+	return transfer.status, nil
+}
+
+func (s *StubSwitch) find(bankCode, accNumber string) (stubAccount, error) {
+	accounts, ok := s.accounts[bankCode]
+	if !ok {
+		return stubAccount{}, ErrUnknownBank
+	}
+	account, ok := accounts[accNumber]
+	if !ok {
+		return stubAccount{}, ErrUnknownAccount
+	}
+	return account, nil
+}
+
+var _ Switch = (*StubSwitch)(nil)
@@ -0,0 +1,68 @@
+package interbank_switch
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestSwitch() (*StubSwitch, *time.Time) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s := NewStubSwitch(time.Minute)
+	s.SetClock(func() time.Time { return now })
+	s.AddBank(Bank{Code: "014", Name: "BCA"})
+	s.AddBank(Bank{Code: "008", Name: "Mandiri"})
+	s.AddAccount("014", "445566", "Andi Wijaya", false)
+	s.AddAccount("014", "556677", "Dewi Lestari", true)
+	return s, &now
+}
+
+func TestNameInquiry(t *testing.T) {
+	s, _ := newTestSwitch()
+
+	if banks := s.Banks(); len(banks) != 2 || banks[0].Code != "008" {
+		t.Errorf("expected banks sorted by code, got %+v", banks)
+	}
+	if name, err := s.NameInquiry("014", "445566"); err != nil || name != "Andi Wijaya" {
+		t.Errorf("expected Andi Wijaya, got %q, %v", name, err)
+	}
+	if _, err := s.NameInquiry("999", "445566"); !errors.Is(err, ErrUnknownBank) {
+		t.Errorf("expected ErrUnknownBank, got %v", err)
+	}
+	if _, err := s.NameInquiry("008", "445566"); !errors.Is(err, ErrUnknownAccount) {
+		t.Errorf("expected ErrUnknownAccount, got %v", err)
+	}
+}
+
+func TestTransferSettlement(t *testing.T) {
+	s, now := newTestSwitch()
+
+	settles := TransferRequest{Reference: "000001", BankCode: "014", DestAccount: "445566", Amount: 50}
+	rejects := TransferRequest{Reference: "000002", BankCode: "014", DestAccount: "556677", Amount: 50}
+	for _, req := range []TransferRequest{settles, rejects} {
+		if status, err := s.Transfer(req); err != nil || status != StatusPending {
+			t.Fatalf("expected PENDING, got %s, %v", status, err)
+		}
+	}
+	if _, err := s.Transfer(settles); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("expected ErrDuplicate, got %v", err)
+	}
+	if status, err := s.Transfer(TransferRequest{Reference: "000003", BankCode: "008", DestAccount: "445566"}); status != StatusRejected || !errors.Is(err, ErrUnknownAccount) {
+		t.Errorf("expected REJECTED with ErrUnknownAccount, got %s, %v", status, err)
+	}
+
+	if status, _ := s.Status("000001"); status != StatusPending {
+		t.Errorf("expected PENDING before settlement, got %s", status)
+	}
+
+	*now = now.Add(time.Minute)
+	if status, _ := s.Status("000001"); status != StatusSettled {
+		t.Errorf("expected SETTLED, got %s", status)
+	}
+	if status, _ := s.Status("000002"); status != StatusRejected {
+		t.Errorf("expected REJECTED, got %s", status)
+	}
+	if _, err := s.Status("000009"); !errors.Is(err, ErrUnknownReference) {
+		t.Errorf("expected ErrUnknownReference, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package interbank_switch
+
+import "errors"
+
+var (
+	ErrUnknownBank      = errors.New("unknown bank code")
+	ErrUnknownAccount   = errors.New("account not found at beneficiary bank")
+	ErrUnknownReference = errors.New("unknown transfer reference")
+	ErrDuplicate        = errors.New("duplicate transfer reference")
+)
+
+type Bank struct {
+	Code string
+	Name string
+}
+
+type Status string
+
+const (
+	StatusPending  Status = "PENDING"
+	StatusSettled  Status = "SETTLED"
+	StatusRejected Status = "REJECTED"
+)
+
+type TransferRequest struct {
+	Reference     string
+	SourceAccount string
+	SourceName    string
+	BankCode      string
+	DestAccount   string
+	Amount        int
+}
+
+// Switch routes transfers to other banks. Transfer only accepts a request;
+// whether the beneficiary bank settles or rejects it is learned later from
+// Status.
+type Switch interface {
+	Banks() []Bank
+	// NameInquiry returns the name on the account at the given bank.
+	NameInquiry(bankCode, accNumber string) (string, error)
+	Transfer(req TransferRequest) (Status, error)
+	Status(reference string) (Status, error)
+}
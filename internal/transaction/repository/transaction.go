@@ -0,0 +1,126 @@
+package transaction_repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type TransactionType string
+
+const (
+	TypeWithdraw    TransactionType = "WITHDRAW"
+	TypeDeposit     TransactionType = "DEPOSIT"
+	TypeTransferOut TransactionType = "TRANSFER_OUT"
+	TypeTransferIn  TransactionType = "TRANSFER_IN"
+	TypeFee         TransactionType = "FEE"
+	TypeFeeIncome   TransactionType = "FEE_INCOME"
+	// TypeInterbankOut is a transfer to another bank. It stays PENDING until
+	// the switch rejects it (REVERSED) or settles it, when the debit is
+	// recorded as a new SUCCESS entry and the PENDING one becomes SETTLED.
+	TypeInterbankOut TransactionType = "INTERBANK_OUT"
+)
+
+type TransactionStatus string
+
+const (
+	StatusSuccess  TransactionStatus = "SUCCESS"
+	StatusFailed   TransactionStatus = "FAILED"
+	StatusPending  TransactionStatus = "PENDING"
+	StatusReversed TransactionStatus = "REVERSED"
+	// StatusSettled marks a pending entry whose debit was posted later, as
+	// the entry named by SettledBy.
+	StatusSettled TransactionStatus = "SETTLED"
+)
+
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+type Transaction struct {
+	ID            string            `json:"id"`
+	Timestamp     time.Time         `json:"timestamp"`
+	AccountNumber string            `json:"account_number"`
+	Type          TransactionType   `json:"type"`
+	Amount        int               `json:"amount"`
+	Counterparty  string            `json:"counterparty,omitempty"`
+	Reference     string            `json:"reference,omitempty"`
+	Balance       int               `json:"balance"`
+	Status        TransactionStatus `json:"status"`
+	Reason        string            `json:"reason,omitempty"`
+	SettledBy     string            `json:"settled_by,omitempty"`
+}
+
+type LedgerStore interface {
+	// Record assigns the transaction an ID and appends it to the ledger.
+	Record(trx Transaction) Transaction
+	// FindByAccount returns the account's transactions with from <= Timestamp < to
+	// in the order they were recorded. A zero from or to leaves that side open.
+	FindByAccount(number string, from, to time.Time) []Transaction
+	// FindAll is FindByAccount across every account.
+	FindAll(from, to time.Time) []Transaction
+	// Update applies update to the transaction with the given ID, e.g. to
+	// close a pending one. The ID cannot change.
+	Update(id string, update func(trx *Transaction)) error
+}
+
+type TransactionRepository struct {
+	mu           sync.RWMutex
+	transactions []Transaction
+}
+
+func NewTransactionRepository() *TransactionRepository {
+	return &TransactionRepository{}
+}
+
+func (r *TransactionRepository) Record(trx Transaction) Transaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trx.ID = fmt.Sprintf("TRX%06d", len(r.transactions)+1)
+	r.transactions = append(r.transactions, trx)
+	return trx
+}
+
+func (r *TransactionRepository) Update(id string, update func(trx *Transaction)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.transactions {
+		if r.transactions[i].ID == id {
+			update(&r.transactions[i])
+			r.transactions[i].ID = id
+			return nil
+		}
+	}
+	return ErrTransactionNotFound
+}
+
+func (r *TransactionRepository) FindByAccount(number string, from, to time.Time) []Transaction {
+	return r.find(func(trx Transaction) bool { return trx.AccountNumber == number }, from, to)
+}
+
+func (r *TransactionRepository) FindAll(from, to time.Time) []Transaction {
+	return r.find(func(Transaction) bool { return true }, from, to)
+}
+
+func (r *TransactionRepository) find(match func(trx Transaction) bool, from, to time.Time) []Transaction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []Transaction
+	for _, trx := range r.transactions {
+		if !match(trx) {
+			continue
+		}
+		if !from.IsZero() && trx.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !trx.Timestamp.Before(to) {
+			continue
+		}
+		result = append(result, trx)
+	}
+	return result
+}
+
+var _ LedgerStore = (*TransactionRepository)(nil)
@@ -0,0 +1,90 @@
+package transaction_repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	repo := NewTransactionRepository()
+
+	first := repo.Record(Transaction{AccountNumber: "123456", Type: TypeWithdraw, Amount: 50})
+	second := repo.Record(Transaction{AccountNumber: "123456", Type: TypeDeposit, Amount: 20})
+
+	if first.ID == "" || first.ID == second.ID {
+		t.Errorf("expected unique ids, got %q and %q", first.ID, second.ID)
+	}
+}
+
+func TestFindByAccount(t *testing.T) {
+	repo := NewTransactionRepository()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	repo.Record(Transaction{AccountNumber: "123456", Timestamp: day.Add(-time.Hour), Amount: 10})
+	repo.Record(Transaction{AccountNumber: "123456", Timestamp: day.Add(time.Hour), Amount: 20})
+	repo.Record(Transaction{AccountNumber: "654321", Timestamp: day.Add(time.Hour), Amount: 30})
+	repo.Record(Transaction{AccountNumber: "123456", Timestamp: day.Add(25 * time.Hour), Amount: 40})
+
+	tests := []struct {
+		from     time.Time
+		to       time.Time
+		expected []int
+	}{
+		{expected: []int{10, 20, 40}},
+		{from: day, expected: []int{20, 40}},
+		{to: day, expected: []int{10}},
+		{from: day, to: day.Add(24 * time.Hour), expected: []int{20}},
+		{from: day.Add(48 * time.Hour), expected: nil},
+	}
+
+	for _, test := range tests {
+		result := repo.FindByAccount("123456", test.from, test.to)
+		if len(result) != len(test.expected) {
+			t.Errorf("from %v to %v: expected %d transactions, got %d", test.from, test.to, len(test.expected), len(result))
+			continue
+		}
+		for i, trx := range result {
+			if trx.Amount != test.expected[i] {
+				t.Errorf("from %v to %v: expected amount %d at %d, got %d", test.from, test.to, test.expected[i], i, trx.Amount)
+			}
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	repo := NewTransactionRepository()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	repo.Record(Transaction{AccountNumber: "123456", Timestamp: day.Add(-time.Hour), Amount: 10})
+	repo.Record(Transaction{AccountNumber: "654321", Timestamp: day.Add(time.Hour), Amount: 20})
+	repo.Record(Transaction{AccountNumber: "123456", Timestamp: day.Add(2 * time.Hour), Amount: 30})
+
+	result := repo.FindAll(day, time.Time{})
+	if len(result) != 2 || result[0].Amount != 20 || result[1].Amount != 30 {
+		t.Errorf("expected amounts 20 and 30, got %+v", result)
+	}
+	if len(repo.FindAll(time.Time{}, time.Time{})) != 3 {
+		t.Errorf("expected 3 transactions, got %d", len(repo.FindAll(time.Time{}, time.Time{})))
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	repo := NewTransactionRepository()
+	trx := repo.Record(Transaction{AccountNumber: "123456", Amount: 10, Status: StatusPending})
+
+	err := repo.Update(trx.ID, func(trx *Transaction) {
+		trx.Status = StatusSuccess
+		trx.ID = "changed"
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	result := repo.FindByAccount("123456", time.Time{}, time.Time{})
+	if result[0].Status != StatusSuccess || result[0].ID != trx.ID {
+		t.Errorf("expected %s to be SUCCESS, got %+v", trx.ID, result[0])
+	}
+
+	if err := repo.Update("TRX999999", func(*Transaction) {}); err != ErrTransactionNotFound {
+		t.Errorf("expected ErrTransactionNotFound, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"fmt"
+	"io"
 	"time"
 )
 
@@ -14,8 +15,8 @@ func CurrencyFormatter(n int) string {
 	return fmt.Sprintf("$%d", n)
 }
 
-func ErrorMessage(s string) {
-	fmt.Println("==========================================")
-	fmt.Println("" + s)
-	fmt.Println("==========================================")
+func ErrorMessage(w io.Writer, s string) {
+	fmt.Fprintln(w, "==========================================")
+	fmt.Fprintln(w, ""+s)
+	fmt.Fprintln(w, "==========================================")
 }
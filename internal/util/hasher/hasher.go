@@ -0,0 +1,95 @@
+package hasher
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+const (
+	scheme     = "pbkdf2-sha256"
+	iterations = 50000
+	saltLength = 16
+	keyLength  = 32
+)
+
+// HashPIN returns a salted PBKDF2-HMAC-SHA256 hash of pin encoded as
+// "pbkdf2-sha256$<iterations>$<salt>$<hash>".
+func HashPIN(pin string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := pbkdf2([]byte(pin), salt, iterations, keyLength)
+	return strings.Join([]string{
+		scheme,
+		strconv.Itoa(iterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// VerifyPIN reports whether pin matches stored in constant time. Values that
+// are not hashes are compared as legacy plaintext PINs.
+func VerifyPIN(pin, stored string) bool {
+	if !IsHashed(stored) {
+		return subtle.ConstantTimeCompare([]byte(pin), []byte(stored)) == 1
+	}
+
+	parts := strings.Split(stored, "$")
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	key := pbkdf2([]byte(pin), salt, iter, len(expected))
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+func IsHashed(stored string) bool {
+	parts := strings.Split(stored, "$")
+	return len(parts) == 4 && parts[0] == scheme
+}
+
+// pbkdf2 implements PBKDF2 from RFC 8018 with HMAC-SHA256 as the PRF.
+func pbkdf2(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	key := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, uint32(block)))
+		u = prf.Sum(u[:0])
+
+		t := make([]byte, hashLen)
+		copy(t, u)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
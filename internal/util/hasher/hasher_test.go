@@ -0,0 +1,64 @@
+package hasher
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		iter     int
+		keyLen   int
+		expected string
+	}{
+		{iter: 1, keyLen: 32, expected: "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{iter: 2, keyLen: 32, expected: "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{iter: 4096, keyLen: 32, expected: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{iter: 1, keyLen: 40, expected: "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b4dbf3a2f3dad3377"},
+	}
+
+	for _, test := range tests {
+		key := hex.EncodeToString(pbkdf2([]byte("password"), []byte("salt"), test.iter, test.keyLen))
+		if key != test.expected {
+			t.Errorf("for %d iterations expected %s, got %s", test.iter, test.expected, key)
+		}
+	}
+}
+
+func TestHashAndVerifyPIN(t *testing.T) {
+	hash, err := HashPIN("482915")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !IsHashed(hash) || strings.Contains(hash, "482915") {
+		t.Errorf("expected an encoded hash, got %s", hash)
+	}
+
+	other, _ := HashPIN("482915")
+	if other == hash {
+		t.Errorf("expected different salts to give different hashes")
+	}
+
+	if !VerifyPIN("482915", hash) {
+		t.Errorf("expected PIN to match its hash")
+	}
+	if VerifyPIN("482916", hash) {
+		t.Errorf("expected wrong PIN not to match")
+	}
+	if VerifyPIN("482915", "pbkdf2-sha256$x$y$z") {
+		t.Errorf("expected malformed hash not to match")
+	}
+}
+
+func TestVerifyPlaintextPIN(t *testing.T) {
+	if IsHashed("123123") {
+		t.Errorf("expected plaintext not to be reported as hashed")
+	}
+	if !VerifyPIN("123123", "123123") {
+		t.Errorf("expected plaintext PIN to match")
+	}
+	if VerifyPIN("123124", "123123") {
+		t.Errorf("expected wrong plaintext PIN not to match")
+	}
+}